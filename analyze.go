@@ -0,0 +1,328 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rpetrich/tsgo/analyzer"
+	"github.com/rpetrich/tsgo/checker"
+	"golang.org/x/tools/go/packages"
+)
+
+// loadOptions are the flags selecting the code to analyze and the checks to
+// run, shared by tsgo and the subcommands that run the analysis.
+type loadOptions struct {
+	tags, goos, goarch string
+	overlay            string
+	stdin              bool
+	stdinFilename      string
+	excludes           stringList
+	includeGenerated   bool
+	tests              bool
+	configFile         string
+	preset             string
+	enable, disable    stringList
+	enableOnly         stringList
+	severity           stringList
+	baseline           string
+	strict             bool
+
+	// config is the configuration file in effect, loaded by resolveConfig.
+	config *fileConfig
+}
+
+func (o *loadOptions) register(flags *flag.FlagSet) {
+	flags.StringVar(&o.tags, "tags", "", "comma-separated list of build tags to apply")
+	flags.StringVar(&o.goos, "goos", "", "target operating system (defaults to $GOOS)")
+	flags.StringVar(&o.goarch, "goarch", "", "target architecture (defaults to $GOARCH)")
+	flags.StringVar(&o.overlay, "overlay", "", "JSON file mapping file paths to replacement contents")
+	flags.BoolVar(&o.stdin, "stdin", false, "read a single file from standard input (requires -stdin-filename)")
+	flags.StringVar(&o.stdinFilename, "stdin-filename", "", "path of the file read by -stdin, used to locate its package and report positions")
+	flags.Var(&o.excludes, "exclude", "comma-separated glob patterns of paths to skip (may be repeated)")
+	flags.BoolVar(&o.includeGenerated, "include-generated", false, "also analyze files marked \"Code generated ... DO NOT EDIT.\"")
+	flags.BoolVar(&o.tests, "test", false, "also analyze the _test.go files of each package")
+	flags.StringVar(&o.preset, "preset", "", "preset check selection to start from: "+presetNames()+" (overrides the configuration file's)")
+	flags.Var(&o.enable, "enable", "comma-separated checks, by ID, name or group, to run in addition to those configured")
+	flags.Var(&o.disable, "disable", "comma-separated checks, by ID, name or group, not to run")
+	flags.Var(&o.enableOnly, "enable-only", "comma-separated checks, by ID, name or group, to run instead of those configured")
+	flags.Var(&o.severity, "severity", "comma-separated check=level pairs overriding the severity of checks; level is error, warning or info")
+	flags.BoolVar(&o.strict, "strict", false, "require //tsgo:ignore directives to name a check and give a reason; others are reported and ignored")
+	flags.StringVar(&o.baseline, "baseline", "", "report only findings not recorded in this file by tsgo baseline write")
+	flags.StringVar(&o.configFile, "config", "", "configuration file to use instead of the nearest "+configFileName+"; \"none\" to use none")
+}
+
+// validate reports usage errors in the options given the positional
+// arguments, and loads the configuration file that applies.
+func (o *loadOptions) validate(args []string) error {
+	if o.stdin && (o.stdinFilename == "" || len(args) > 0) {
+		return fmt.Errorf("-stdin requires -stdin-filename and no package arguments")
+	}
+	if err := validatePreset(o.preset); err != nil {
+		return err
+	}
+	path := o.configFile
+	if path == "" {
+		var err error
+		if path, err = findConfig(configDir(o, args)); err != nil {
+			return err
+		}
+	}
+	o.config = &fileConfig{}
+	if path != "" && path != "none" {
+		config, err := loadConfig(path)
+		if err != nil {
+			return err
+		}
+		o.config = config
+	}
+	for _, pair := range o.severity {
+		name, level, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid -severity %q (want check=level)", pair)
+		}
+		if analyzer.Lookup(name) == nil {
+			return fmt.Errorf("unknown check %q (see tsgo list-checks)", name)
+		}
+		if err := analyzer.Severity(level).Validate(); err != nil {
+			return err
+		}
+	}
+	for _, names := range []stringList{o.enable, o.disable, o.enableOnly} {
+		for _, name := range names {
+			if analyzer.Resolve(name) == nil {
+				return fmt.Errorf("unknown check %q (see tsgo list-checks)", name)
+			}
+		}
+	}
+	return nil
+}
+
+// severities returns the severity overrides of the configuration file
+// updated with those given by -severity, keyed by check name.
+func (o *loadOptions) severities() map[string]analyzer.Severity {
+	severities := map[string]analyzer.Severity{}
+	for key, severity := range o.config.Severity {
+		severities[analyzer.Lookup(key).Name] = severity
+	}
+	for _, pair := range o.severity {
+		key, level, _ := strings.Cut(pair, "=")
+		severities[analyzer.Lookup(key).Name] = analyzer.Severity(level)
+	}
+	return severities
+}
+
+// activePreset returns the preset in effect: that given by -preset, else
+// that of the configuration file, else the default one.
+func (o *loadOptions) activePreset() analyzer.Preset {
+	name := "default"
+	switch {
+	case o.preset != "":
+		name = o.preset
+	case o.config.Preset != "":
+		name = o.config.Preset
+	}
+	p, _ := analyzer.LookupPreset(name)
+	return p
+}
+
+// checkSelection returns the checks to enable, include and disable, as in
+// checker.Options, combining the preset and the configuration file with the
+// flags. -enable and -disable adjust the configured selection, and
+// -enable-only replaces it.
+func (o *loadOptions) checkSelection() (enable, include, disable []string) {
+	// names expands keys, check names, IDs or groups, to check names.
+	names := func(keys ...string) []string {
+		var names []string
+		for _, key := range keys {
+			for _, check := range analyzer.Resolve(key) {
+				names = append(names, check.Name)
+			}
+		}
+		return names
+	}
+	if len(o.enableOnly) > 0 {
+		enable = names(o.enableOnly...)
+	} else {
+		p := o.activePreset()
+		enable = names(o.config.Enable...)
+		if len(enable) > 0 {
+			enable = append(enable, names(o.enable...)...)
+		}
+		include = append(include, p.Include...)
+		enabled := map[string]bool{}
+		for _, n := range names(append(o.enable, o.config.Enable...)...) {
+			enabled[n] = true
+			include = append(include, n)
+		}
+		for _, n := range names(append(p.Disable, o.config.Disable...)...) {
+			if !enabled[n] {
+				disable = append(disable, n)
+			}
+		}
+	}
+	disable = append(disable, names(o.disable...)...)
+	return enable, include, disable
+}
+
+// analyze loads the packages matched by patterns, runs the checks over them
+// and returns a report of the diagnostics in the files being reported on. It
+// returns an error if the packages, or the overlay, exclusions or baseline
+// the options name, cannot be read.
+func analyze(opts *loadOptions, patterns []string) (*report, error) {
+	start := time.Now()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	cfg := &packages.Config{Tests: opts.tests}
+	if opts.tags != "" {
+		cfg.BuildFlags = append(cfg.BuildFlags, "-tags="+opts.tags)
+	}
+	if opts.goos != "" || opts.goarch != "" {
+		cfg.Env = os.Environ()
+		if opts.goos != "" {
+			cfg.Env = append(cfg.Env, "GOOS="+opts.goos)
+		}
+		if opts.goarch != "" {
+			cfg.Env = append(cfg.Env, "GOARCH="+opts.goarch)
+		}
+	}
+	if opts.overlay != "" {
+		contents, err := readOverlay(opts.overlay)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Overlay = contents
+	}
+	var stdinPath string
+	if opts.stdin {
+		contents, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		stdinPath, err = filepath.Abs(opts.stdinFilename)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Overlay == nil {
+			cfg.Overlay = map[string][]byte{}
+		}
+		cfg.Overlay[stdinPath] = contents
+		patterns = []string{"file=" + stdinPath}
+	}
+	moduleDirs, err := workspaceModuleDirs(cfg.Env)
+	if err != nil {
+		return nil, err
+	}
+	patterns, err = expandWorkspacePatterns(patterns, moduleDirs)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := checker.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	r := &report{Notes: loadNotes(pkgs)}
+
+	filter, err := newPathFilter(opts.excludes)
+	if err != nil {
+		return nil, err
+	}
+	if err := filter.addExcludes(opts.config.dir, opts.config.Exclude); err != nil {
+		return nil, err
+	}
+
+	var roots []*packages.Package
+	reportable := map[string]bool{}
+	for _, pkg := range withoutTestDuplicates(pkgs) {
+		if isSkipped(pkg) {
+			continue
+		}
+		roots = append(roots, pkg)
+		files := excludeFiles(pkg, sourceFiles(pkg), filter)
+		if !opts.includeGenerated {
+			files = withoutGenerated(pkg, files)
+		}
+		for _, f := range files {
+			reportable[pkg.Fset.Position(f.Package).Filename] = true
+			r.Functions += countFunctions(f)
+		}
+	}
+	if stdinPath != "" {
+		reportable = map[string]bool{stdinPath: reportable[stdinPath]}
+	}
+
+	enable, include, disable := opts.checkSelection()
+	var nestedSeverity analyzer.Severity
+	if len(opts.enableOnly) == 0 {
+		nestedSeverity = opts.activePreset().NestedSeverity
+	}
+	diagnostics, err := checker.Check(roots, checker.Options{
+		Enable:             enable,
+		Include:            include,
+		Disable:            disable,
+		Severity:           opts.severities(),
+		NestedSeverity:     nestedSeverity,
+		SafeTypes:          opts.config.SafeTypes,
+		SafeInterfaces:     opts.config.SafeInterfaces,
+		Sanitizers:         opts.config.Sanitizers,
+		AsyncCallbacks:     opts.config.AsyncCallbacks,
+		SpawnWrappers:      opts.config.SpawnWrappers,
+		BlockingFuncs:      opts.config.BlockingFuncs,
+		UnsafeTypes:        opts.config.UnsafeTypes,
+		MinCriticalSection: opts.config.MinCriticalSection,
+		StrictSuppressions: opts.strict,
+	})
+	if err != nil {
+		r.Notes = append(r.Notes, note{Pos: "-", Message: err.Error()})
+	}
+	for _, d := range diagnostics {
+		if reportable[d.Pos.Filename] {
+			r.Diagnostics = append(r.Diagnostics, d)
+		}
+	}
+	if opts.baseline != "" {
+		if r.Diagnostics, r.Baselined, err = filterBaseline(r.Diagnostics, opts.baseline); err != nil {
+			return nil, err
+		}
+	}
+	r.Checks, _ = analyzer.Config{Enable: enable, Include: include, Disable: disable}.Checks()
+	r.Packages = len(roots)
+	for _, ok := range reportable {
+		if ok {
+			r.Files++
+		}
+	}
+	r.Elapsed = time.Since(start)
+	r.Root, r.Relative = filter.root, filter.relative
+	r.ReadFile = func(filename string) ([]byte, error) {
+		if contents, ok := cfg.Overlay[filename]; ok {
+			return contents, nil
+		}
+		return os.ReadFile(filename)
+	}
+	return r, nil
+}
+
+// countFunctions returns the number of function declarations and literals
+// with bodies in f.
+func countFunctions(f *ast.File) int {
+	n := 0
+	ast.Inspect(f, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncDecl:
+			if node.Body != nil {
+				n++
+			}
+		case *ast.FuncLit:
+			n++
+		}
+		return true
+	})
+	return n
+}
@@ -0,0 +1,282 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// Config selects the checks run by an Analyzer built with New, by name, ID or
+// group.
+// When Enable is empty every check not marked OffByDefault is enabled, along
+// with those named in Include; checks named in Disable are then removed.
+type Config struct {
+	Enable  []string
+	Include []string
+	Disable []string
+	// SafeTypes lists fully qualified types, such as "*database/sql.DB",
+	// that are safe to share between goroutines and never reported as
+	// containing pointers. Entries may also be patterns matching several
+	// named types, such as "*go.uber.org/zap.*"; see typePattern.
+	SafeTypes []string
+	// SafeInterfaces lists fully qualified marker interfaces, such as
+	// "github.com/mycorp/conc.ConcurrencySafe", whose implementations are
+	// safe to share, letting library authors opt their types in.
+	SafeInterfaces []string
+	// Sanitizers lists functions and methods, by the full name
+	// types.Func.FullName gives them, that return a fresh deep copy of their
+	// argument, such as "google.golang.org/protobuf/proto.Clone", or that
+	// copy into the value their first argument points to and return at most
+	// an error, such as "github.com/jinzhu/copier.Copy". Payloads produced by
+	// them share nothing and are not reported.
+	Sanitizers []string
+	// AsyncCallbacks adds functions, by full name as in Sanitizers, and func
+	// types, by qualified name, such as "github.com/acme/sched.Every" or
+	// "github.com/acme/events.Handler", to those whose function arguments
+	// are called later on another goroutine, like time.AfterFunc.
+	AsyncCallbacks []string
+	// SpawnWrappers adds functions, by full name as in Sanitizers, that
+	// start a goroutine running their function argument, such as
+	// "(*github.com/acme/workers.Pool).Submit", to those treated like go
+	// statements.
+	SpawnWrappers []string
+	// BlockingFuncs adds functions, by full name as in Sanitizers, that may
+	// block for a long time, such as "(*github.com/acme/rpc.Client).Call",
+	// to those lock-across-blocking reports calls of under a lock.
+	BlockingFuncs []string
+	// UnsafeTypes adds types, by name or pattern as in SafeTypes, to those
+	// known not to be safe for concurrent use, keyed by the check reporting
+	// them: shared-db-handle, shared-rand, shared-buffer or shared-template.
+	UnsafeTypes map[string][]string
+	// MinCriticalSection is the number of statements between a Lock and its
+	// Unlock up to which undeferred-unlock stays quiet.
+	MinCriticalSection int
+	// StrictSuppressions makes //tsgo:ignore directives that do not name a
+	// check and give a reason ineffective, and reports them.
+	StrictSuppressions bool
+	// Severity overrides the default severity of checks, keyed by check
+	// name or ID, and NestedSeverity, if set, is the severity of findings
+	// about pointers nested in struct or array payloads for checks without
+	// an entry in Severity. Diagnostics carry no severity, so when either is
+	// set every message starts with that of its finding, such as "info: ",
+	// for drivers such as golangci-lint to match on.
+	Severity       map[string]Severity
+	NestedSeverity Severity
+}
+
+// Analyzer runs every tsgo check over a package.
+var Analyzer = mustNew(Config{})
+
+// New returns an Analyzer running the checks selected by config. Besides
+// reporting diagnostics, its result is the []*Finding it reported, for drivers
+// that want more structure than a message string.
+//
+// The selection is resolved each time the analyzer runs, so checks registered
+// after New returns, typically from another package's init function, are
+// still run when config does not name checks explicitly.
+func New(config Config) (*analysis.Analyzer, error) {
+	if _, err := selectChecks(config); err != nil {
+		return nil, err
+	}
+	if _, err := newTypeSet(config.SafeTypes); err != nil {
+		return nil, err
+	}
+	if _, err := newUnsafeTypeSets(config.UnsafeTypes); err != nil {
+		return nil, err
+	}
+	if _, err := resolveSeverities(config); err != nil {
+		return nil, err
+	}
+	return &analysis.Analyzer{
+		Name:     "tsgo",
+		Doc:      "report pointers shared between goroutines through go statements, channel sends and global variables",
+		URL:      "https://github.com/rpetrich/tsgo",
+		Requires: []*analysis.Analyzer{inspect.Analyzer, Facts},
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			selected, err := selectChecks(config)
+			if err != nil {
+				return nil, err
+			}
+			return run(pass, selected, config)
+		},
+		RunDespiteErrors: true,
+		ResultType:       reflect.TypeOf([]*Finding(nil)),
+	}, nil
+}
+
+// Checks returns the registered checks config selects, in the order they
+// run.
+func (config Config) Checks() ([]*Check, error) {
+	return selectChecks(config)
+}
+
+func selectChecks(config Config) ([]*Check, error) {
+	registered := Checks()
+	enabled := map[string]bool{}
+	for _, check := range registered {
+		enabled[check.Name] = len(config.Enable) == 0 && !check.OffByDefault
+	}
+	byKey := map[string][]*Check{}
+	for _, names := range [][]string{config.Enable, config.Include, config.Disable} {
+		for _, name := range names {
+			if byKey[name] = Resolve(name); byKey[name] == nil {
+				return nil, fmt.Errorf("unknown check %q", name)
+			}
+		}
+	}
+	set := func(names []string, value bool) {
+		for _, name := range names {
+			for _, check := range byKey[name] {
+				enabled[check.Name] = value
+			}
+		}
+	}
+	set(config.Enable, true)
+	if len(config.Enable) == 0 {
+		set(config.Include, true)
+	}
+	set(config.Disable, false)
+	var selected []*Check
+	for _, check := range registered {
+		if enabled[check.Name] {
+			selected = append(selected, check)
+		}
+	}
+	return selected, nil
+}
+
+// resolveSeverities returns config.Severity keyed by check name, or nil if
+// config sets no severities at all.
+func resolveSeverities(config Config) (map[string]Severity, error) {
+	if len(config.Severity) == 0 && config.NestedSeverity == "" {
+		return nil, nil
+	}
+	severities := map[string]Severity{}
+	for key, severity := range config.Severity {
+		check := Lookup(key)
+		if check == nil {
+			return nil, fmt.Errorf("unknown check %q", key)
+		}
+		if err := severity.Validate(); err != nil {
+			return nil, err
+		}
+		severities[check.Name] = severity
+	}
+	if config.NestedSeverity != "" {
+		if err := config.NestedSeverity.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return severities, nil
+}
+
+func mustNew(config Config) *analysis.Analyzer {
+	a, err := New(config)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func run(pass *analysis.Pass, selected []*Check, config Config) (interface{}, error) {
+	files := make([]*ast.File, 0, len(pass.Files))
+	for _, f := range pass.Files {
+		if !isCgoSupportFile(pass.Fset, f) {
+			files = append(files, f)
+		}
+	}
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	if len(files) != len(pass.Files) {
+		inspect = inspector.New(files)
+	}
+	facts := pass.ResultOf[Facts].(*factSet)
+	safeTypes, err := newTypeSet(config.SafeTypes)
+	if err != nil {
+		return nil, err
+	}
+	safeTypes.addMarkers(pass.Pkg, config.SafeInterfaces)
+	var findings []*Finding
+	suppressions := collectSuppressions(pass, files)
+	transferChans := collectTransferChans(pass, files)
+	sanitizers := map[string]bool{}
+	for _, name := range config.Sanitizers {
+		sanitizers[name] = true
+	}
+	freshVars := collectFreshVars(pass, files, sanitizers)
+	asyncCallbackSet := map[string]bool{}
+	for _, names := range [][]string{asyncCallbacks, config.AsyncCallbacks} {
+		for _, name := range names {
+			asyncCallbackSet[name] = true
+		}
+	}
+	spawnWrapperSet := map[string]bool{}
+	for _, names := range [][]string{spawnWrappers, config.SpawnWrappers} {
+		for _, name := range names {
+			spawnWrapperSet[name] = true
+		}
+	}
+	blockingFuncSet := map[string]bool{}
+	for _, names := range [][]string{blockingFuncs, config.BlockingFuncs} {
+		for _, name := range names {
+			blockingFuncSet[name] = true
+		}
+	}
+	chanFlow := collectChanFlow(pass, files, facts)
+	unsafeTypeSets, err := newUnsafeTypeSets(config.UnsafeTypes)
+	if err != nil {
+		return nil, err
+	}
+	severities, err := resolveSeverities(config)
+	if err != nil {
+		return nil, err
+	}
+	newPass := func(check *Check) *Pass {
+		return &Pass{
+			Pass:               pass,
+			Check:              check,
+			Files:              files,
+			Inspector:          inspect,
+			facts:              facts,
+			findings:           &findings,
+			safeTypes:          safeTypes,
+			suppressions:       suppressions,
+			strictSuppressions: config.StrictSuppressions,
+			transferChans:      transferChans,
+			sanitizers:         sanitizers,
+			freshVars:          freshVars,
+			asyncCallbacks:     asyncCallbackSet,
+			spawnWrappers:      spawnWrapperSet,
+			blockingFuncs:      blockingFuncSet,
+			chanFlow:           chanFlow,
+			unsafeTypeSets:     unsafeTypeSets,
+			minCriticalSection: config.MinCriticalSection,
+			severities:         severities,
+			nestedSeverity:     config.NestedSeverity,
+		}
+	}
+	for _, check := range selected {
+		check.Run(newPass(check))
+	}
+	for _, check := range selected {
+		if check.Name == suppressionCheckName {
+			reportUnusedSuppressions(newPass(check), selected)
+		}
+	}
+	return findings, nil
+}
+
+// isCgoSupportFile reports whether f is one of the files cgo generates to
+// support a package rather than the translation of a file written by the
+// user. Translated files carry //line directives back to the original
+// source; support files have none and live under generated names.
+func isCgoSupportFile(fset *token.FileSet, f *ast.File) bool {
+	filename := fset.Position(f.Package).Filename
+	return strings.HasPrefix(filepath.Base(filename), "_cgo_") || !strings.HasSuffix(filename, ".go")
+}
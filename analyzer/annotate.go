@@ -0,0 +1,231 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// threadsafeDirective, in the doc comment of a type declaration, marks the
+// type as safe to share between goroutines:
+//
+//	// Cache is safe for concurrent use.
+//	//
+//	//tsgo:threadsafe
+//	type Cache struct { ... }
+//
+// Values of the type, pointers to it and values containing either are then
+// not reported as sharing memory. The annotation is exported as a typeFact,
+// so it applies in importing packages too.
+const threadsafeDirective = "//tsgo:threadsafe"
+
+// typeFact records the annotations of a type declaration.
+type typeFact struct {
+	ThreadSafe bool
+}
+
+func (*typeFact) AFact() {}
+
+func (f *typeFact) String() string {
+	if f.ThreadSafe {
+		return "threadsafe"
+	}
+	return "-"
+}
+
+// guardedByDirective, in the doc or line comment of a struct field or
+// package-level variable, names the mutex that must be held to access it:
+//
+//	type Counter struct {
+//		mu sync.Mutex
+//		n  int //tsgo:guardedby mu
+//	}
+//
+// For a field, the mutex is another field of the same struct; for a
+// variable, a package-level variable. The guarded-by check verifies the
+// accesses.
+const guardedByDirective = "//tsgo:guardedby"
+
+// ownsDirective, in the doc comment of a function, names parameters the
+// function takes ownership of: callers hand the value over and must not use
+// it afterwards, so passing it is not reported as sharing.
+//
+//	//tsgo:owns buf
+//	func consume(buf []byte)
+//
+// transfersDirective, in the doc or line comment of a channel variable or
+// field, or at the end of a line declaring one with :=, says that sends on the channel transfer ownership of the value
+// sent; in the doc comment of a function it names channel parameters that
+// do. The use-after-transfer check verifies that the sender leaves
+// transferred values alone.
+//
+//	jobs chan *Job //tsgo:transfers
+const (
+	ownsDirective      = "//tsgo:owns"
+	transfersDirective = "//tsgo:transfers"
+)
+
+// hasDirective reports whether one of the comments of doc is directive,
+// alone or followed by arguments.
+func hasDirective(doc *ast.CommentGroup, directive string) bool {
+	_, c := directiveArgs(doc, directive)
+	return c != nil
+}
+
+// directiveArgs returns the first comment of doc that is directive, and the
+// text following the directive, trimmed. It returns a nil comment if there
+// is none.
+func directiveArgs(doc *ast.CommentGroup, directive string) (string, *ast.Comment) {
+	if doc == nil {
+		return "", nil
+	}
+	for _, c := range doc.List {
+		if rest, ok := strings.CutPrefix(c.Text, directive); ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			return strings.TrimSpace(rest), c
+		}
+	}
+	return "", nil
+}
+
+// directiveNames returns the comma- or space-separated names given to the
+// first directive of doc, and the directive comment, or nil if there is none.
+func directiveNames(doc *ast.CommentGroup, directive string) ([]string, *ast.Comment) {
+	args, c := directiveArgs(doc, directive)
+	return strings.FieldsFunc(args, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }), c
+}
+
+// ownedParams returns the indices of the parameters of fd named by its
+// //tsgo:owns directive. Names matching no parameter are ignored here and
+// reported by the use-after-transfer check.
+func ownedParams(fd *ast.FuncDecl, fn *types.Func) []int {
+	names, _ := directiveNames(fd.Doc, ownsDirective)
+	params := fn.Type().(*types.Signature).Params()
+	var owned []int
+	for i := 0; i < params.Len(); i++ {
+		for _, name := range names {
+			if params.At(i).Name() == name {
+				owned = append(owned, i)
+			}
+		}
+	}
+	return owned
+}
+
+// collectTransferChans returns the channel variables, fields and parameters
+// of files annotated with transfersDirective.
+func collectTransferChans(pass *analysis.Pass, files []*ast.File) map[types.Object]bool {
+	chans := map[types.Object]bool{}
+	mark := func(names []*ast.Ident, docs ...*ast.CommentGroup) {
+		for _, doc := range docs {
+			if hasDirective(doc, transfersDirective) {
+				for _, id := range names {
+					if obj := pass.TypesInfo.Defs[id]; obj != nil {
+						chans[obj] = true
+					}
+				}
+				return
+			}
+		}
+	}
+	for _, f := range files {
+		// Lines ending in the directive, for channels declared with :=.
+		lines := map[int]bool{}
+		for _, group := range f.Comments {
+			if hasDirective(group, transfersDirective) {
+				lines[pass.Fset.Position(group.Pos()).Line] = true
+			}
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				if n.Tok == token.DEFINE && lines[pass.Fset.Position(n.Pos()).Line] {
+					for _, lhs := range n.Lhs {
+						if id, ok := lhs.(*ast.Ident); ok && pass.TypesInfo.Defs[id] != nil {
+							chans[pass.TypesInfo.Defs[id]] = true
+						}
+					}
+				}
+			case *ast.GenDecl:
+				for _, spec := range n.Specs {
+					if vs, ok := spec.(*ast.ValueSpec); ok {
+						doc := vs.Doc
+						if doc == nil && len(n.Specs) == 1 {
+							doc = n.Doc
+						}
+						mark(vs.Names, doc, vs.Comment)
+					}
+				}
+			case *ast.StructType:
+				for _, field := range n.Fields.List {
+					mark(field.Names, field.Doc, field.Comment)
+				}
+			case *ast.FuncDecl:
+				names, _ := directiveNames(n.Doc, transfersDirective)
+				for _, field := range n.Type.Params.List {
+					for _, id := range field.Names {
+						for _, name := range names {
+							if id.Name == name {
+								chans[pass.TypesInfo.Defs[id]] = true
+							}
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	return chans
+}
+
+// collectTypeAnnotations records the annotated type declarations of the
+// package in facts and exports them.
+func collectTypeAnnotations(pass *analysis.Pass, facts *factSet) {
+	for _, f := range pass.Files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				doc := specDoc(gd, ts.Doc)
+				obj, ok := pass.TypesInfo.Defs[ts.Name].(*types.TypeName)
+				if !ok || !hasDirective(doc, threadsafeDirective) {
+					continue
+				}
+				fact := &typeFact{ThreadSafe: true}
+				facts.types[obj] = fact
+				pass.ExportObjectFact(obj, fact)
+			}
+		}
+	}
+}
+
+// specDoc returns doc, the doc comment of a spec of gd, or the doc comment
+// of gd itself when doc is nil and the spec is the only one.
+func specDoc(gd *ast.GenDecl, doc *ast.CommentGroup) *ast.CommentGroup {
+	if doc == nil && len(gd.Specs) == 1 {
+		return gd.Doc
+	}
+	return doc
+}
+
+// threadSafe reports whether t, or the type t points to, is annotated with
+// threadsafeDirective.
+func (s *factSet) threadSafe(t types.Type) bool {
+	if ptr, ok := types.Unalias(t).(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return false
+	}
+	fact := s.types[named.Origin().Obj()]
+	return fact != nil && fact.ThreadSafe
+}
@@ -0,0 +1,288 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+var mixedAtomic = &Check{
+	Name: "mixed-atomic",
+	ID:   "TS0052",
+	Doc:  "report plain reads and writes of fields and variables accessed with sync/atomic elsewhere",
+	Rationale: `Accessing memory with sync/atomic only orders it against other atomic
+accesses. A plain read or write of the same field or variable anywhere else
+is a data race, undefined under the Go memory model, which the race
+detector misses unless a test happens to run both at once. The same holds
+for overwriting a value of one of the sync/atomic types, such as
+atomic.Int64, by assignment instead of calling Store. Use atomic operations
+for every access, or better, declare the field with an atomic type.
+Accesses that cannot race are left alone: those through a variable the
+function has just allocated and not yet handed to anything, such as a
+constructor filling in a new value, and those following a wait for other
+goroutines, such as reading a total after wg.Wait.`,
+	Bad: `func (s *Stats) Hit() {
+	atomic.AddInt64(&s.hits, 1)
+}
+
+func (s *Stats) Hits() int64 {
+	return s.hits
+}`,
+	Good: `func (s *Stats) Hits() int64 {
+	return atomic.LoadInt64(&s.hits)
+}`,
+	Run: func(pass *Pass) {
+		// atomics maps the fields and variables accessed with the functions
+		// of sync/atomic to the first such access; operands holds the
+		// operands of those accesses.
+		atomics := map[types.Object]*ast.CallExpr{}
+		operands := map[ast.Expr]bool{}
+		pass.Inspector.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+			call := n.(*ast.CallExpr)
+			x := atomicOperand(pass.TypesInfo, call)
+			if x == nil {
+				return
+			}
+			x = unqualified(pass.TypesInfo, x)
+			if obj := accessedVar(pass.TypesInfo, x); obj != nil {
+				if _, ok := atomics[obj]; !ok {
+					atomics[obj] = call
+				}
+				operands[x] = true
+			}
+		})
+		writes := map[ast.Expr]bool{}
+		// addressed holds the operands of & outside atomic calls, which take
+		// a pointer rather than access the memory.
+		addressed := map[ast.Expr]bool{}
+		pass.Inspector.Preorder([]ast.Node{(*ast.AssignStmt)(nil), (*ast.IncDecStmt)(nil), (*ast.UnaryExpr)(nil)}, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				for _, lhs := range n.Lhs {
+					writes[unqualified(pass.TypesInfo, writtenExpr(lhs))] = true
+				}
+			case *ast.IncDecStmt:
+				writes[unqualified(pass.TypesInfo, writtenExpr(n.X))] = true
+			case *ast.UnaryExpr:
+				if n.Op == token.AND {
+					addressed[unqualified(pass.TypesInfo, ast.Unparen(n.X))] = true
+				}
+			}
+		})
+		// qualified holds the identifiers of the qualified identifiers seen,
+		// which are reported as a whole.
+		qualified := map[ast.Expr]bool{}
+		pass.Inspector.Preorder([]ast.Node{(*ast.SelectorExpr)(nil), (*ast.Ident)(nil)}, func(n ast.Node) {
+			node := n.(ast.Expr)
+			x := unqualified(pass.TypesInfo, node)
+			if x != node {
+				qualified[x] = true
+			} else if qualified[x] {
+				return
+			}
+			if id, ok := x.(*ast.Ident); ok {
+				if v, ok := pass.TypesInfo.Uses[id].(*types.Var); !ok || v.IsField() {
+					// Fields are accessed through selectors, or named as
+					// the keys of composite literals.
+					return
+				}
+			}
+			obj := accessedVar(pass.TypesInfo, x)
+			if obj == nil || operands[x] || addressed[x] || unpublished(pass, node) || waitedBefore(pass.TypesInfo, enclosingPath(pass, node)) {
+				return
+			}
+			name := stringifyNode(pass.Fset, node)
+			if call, ok := atomics[obj]; ok {
+				access := "reading"
+				if writes[x] {
+					access = "writing"
+				}
+				finding := pass.NewFinding(node, nil, "%s %s without sync/atomic, but it is accessed atomically elsewhere; mixing atomic and plain accesses races", access, name)
+				finding.Relate(call, "%s accessed atomically here", name)
+				pass.ReportFinding(finding)
+			} else if writes[x] && isAtomicType(obj.Type()) {
+				pass.Reportf(node, obj.Type(), "overwriting %s by assignment races with its methods; call Store instead", name)
+			}
+		})
+	},
+}
+
+// atomicOperand returns the expression whose address call passes to a
+// function of sync/atomic, such as s.hits in atomic.AddInt64(&s.hits, 1), or
+// nil if call is no such call.
+func atomicOperand(info *types.Info, call *ast.CallExpr) ast.Expr {
+	fn, ok := calledFunc(info, call)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "sync/atomic" || fn.Type().(*types.Signature).Recv() != nil || len(call.Args) == 0 {
+		return nil
+	}
+	addr, ok := ast.Unparen(call.Args[0]).(*ast.UnaryExpr)
+	if !ok || addr.Op != token.AND {
+		return nil
+	}
+	return ast.Unparen(addr.X)
+}
+
+// accessedVar returns the field or variable expr accesses, if it is an
+// identifier or a field selector.
+func accessedVar(info *types.Info, expr ast.Expr) types.Object {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if v, ok := info.Uses[e].(*types.Var); ok {
+			return v
+		}
+	case *ast.SelectorExpr:
+		if sel := info.Selections[e]; sel != nil && sel.Kind() == types.FieldVal {
+			return sel.Obj()
+		}
+	}
+	return nil
+}
+
+// unpublished reports whether expr accesses a field through a local
+// variable that its function allocated by taking the address of a composite
+// literal or calling new, and has so far used only to access fields of: no
+// other goroutine can see the memory yet. A variable used otherwise later in
+// a loop around expr may have been handed on in an earlier iteration.
+func unpublished(pass *Pass, expr ast.Expr) bool {
+	info := pass.TypesInfo
+	if _, ok := expr.(*ast.SelectorExpr); !ok {
+		return false
+	}
+	id := fieldRoot(info, expr)
+	if id == nil {
+		return false
+	}
+	v, ok := info.Uses[id].(*types.Var)
+	if !ok || v.IsField() {
+		return false
+	}
+	body, _ := enclosingFunc(pass, expr, v.Pos())
+	if body == nil || v.Pos() < body.Pos() || v.Pos() >= body.End() {
+		return false
+	}
+	// Uses up to until, or through the whole of the outermost loop around
+	// expr that starts after v is declared, count.
+	until := expr.Pos()
+	for _, n := range enclosingPath(pass, expr) {
+		switch n := n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			if n.Pos() > v.Pos() {
+				until = n.End()
+			}
+		}
+		if n == body {
+			break
+		}
+	}
+	allocated, published := false, false
+	// fieldBases holds the uses of v selecting a field, other than to take
+	// its address.
+	fieldBases := map[*ast.Ident]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// A function literal using v may run anywhere.
+			ast.Inspect(n, func(n ast.Node) bool {
+				if use, ok := n.(*ast.Ident); ok && info.Uses[use] == v {
+					published = true
+				}
+				return !published
+			})
+			return false
+		case *ast.UnaryExpr:
+			if n.Op == token.AND {
+				// Taking the address of a field hands it out.
+				if base := fieldRoot(info, n.X); base != nil && info.Uses[base] == v && base.Pos() < until {
+					published = true
+				}
+			}
+		case *ast.SelectorExpr:
+			if s := info.Selections[n]; s != nil && s.Kind() == types.FieldVal {
+				if base, ok := ast.Unparen(n.X).(*ast.Ident); ok {
+					fieldBases[base] = true
+				}
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				lid, ok := ast.Unparen(lhs).(*ast.Ident)
+				if !ok {
+					continue
+				}
+				switch {
+				case info.Defs[lid] == v:
+					allocated = len(n.Lhs) == len(n.Rhs) && isAllocation(info, n.Rhs[i])
+				case info.Uses[lid] == v:
+					published = true
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if info.Defs[name] == v {
+					allocated = len(n.Names) == len(n.Values) && isAllocation(info, n.Values[i])
+				}
+			}
+		case *ast.Ident:
+			if info.Uses[n] == v && n.Pos() < until && !fieldBases[n] {
+				published = true
+			}
+		}
+		return !published
+	})
+	return allocated && !published
+}
+
+// fieldRoot returns the variable expr selects a field of, possibly through
+// other fields, such as c in c.stats.n, or nil if expr is no such selection.
+func fieldRoot(info *types.Info, expr ast.Expr) *ast.Ident {
+	for {
+		switch e := ast.Unparen(expr).(type) {
+		case *ast.SelectorExpr:
+			if s := info.Selections[e]; s == nil || s.Kind() != types.FieldVal {
+				return nil
+			}
+			expr = e.X
+		case *ast.Ident:
+			return e
+		default:
+			return nil
+		}
+	}
+}
+
+// isAllocation reports whether expr allocates a new value: &T{...} or
+// new(T).
+func isAllocation(info *types.Info, expr ast.Expr) bool {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.UnaryExpr:
+		_, ok := ast.Unparen(e.X).(*ast.CompositeLit)
+		return e.Op == token.AND && ok
+	case *ast.CallExpr:
+		id, ok := ast.Unparen(e.Fun).(*ast.Ident)
+		if !ok || id.Name != "new" {
+			return false
+		}
+		_, ok = info.Uses[id].(*types.Builtin)
+		return ok
+	}
+	return false
+}
+
+// unqualified returns the identifier of a qualified identifier such as
+// os.Args, and any other expression unchanged.
+func unqualified(info *types.Info, expr ast.Expr) ast.Expr {
+	if sel, ok := expr.(*ast.SelectorExpr); ok && info.Selections[sel] == nil {
+		if id, ok := sel.X.(*ast.Ident); ok {
+			if _, ok := info.Uses[id].(*types.PkgName); ok {
+				return sel.Sel
+			}
+		}
+	}
+	return expr
+}
+
+// isAtomicType reports whether t is one of the types of sync/atomic, such as
+// atomic.Int64 or atomic.Pointer[T].
+func isAtomicType(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "sync/atomic"
+}
@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+var blockedGoroutine = &Check{
+	Name:  "blocked-goroutine",
+	ID:    "TS0023",
+	Group: resourceLeakGroup,
+	Doc:   "report goroutines receiving from channels that are never sent to or closed",
+	Rationale: `A goroutine receiving from a channel that nothing sends to or closes, or
+selecting only on such channels, blocks forever. It leaks, along with
+everything it references, for the life of the program. Only channels the
+package makes and never hands to code it cannot see are considered.`,
+	Bad: `results := make(chan int)
+go func() {
+	for r := range results { // nothing sends on or closes results
+		total += r
+	}
+}()`,
+	Good: `results := make(chan int)
+go func() {
+	for r := range results {
+		total += r
+	}
+}()
+for _, r := range compute() {
+	results <- r
+}
+close(results)`,
+	Run: func(pass *Pass) {
+		// dead reports whether receiving from ch blocks forever.
+		dead := func(ch ast.Expr) bool {
+			ops := pass.chanFlow.of(pass.TypesInfo, ch)
+			return ops.local() && len(ops.sends) == 0 && len(ops.closes) == 0
+		}
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil)}, func(n ast.Node) {
+			lit, ok := ast.Unparen(n.(*ast.GoStmt).Call.Fun).(*ast.FuncLit)
+			if !ok {
+				return
+			}
+			selected := map[ast.Node]bool{}
+			ast.Inspect(lit.Body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncLit:
+					// Function literals may not run on this goroutine.
+					return false
+				case *ast.SelectStmt:
+					blocked := true
+					for _, clause := range n.Body.List {
+						recv := commRecv(clause.(*ast.CommClause))
+						if recv == nil || !dead(recv.X) {
+							blocked = false
+						}
+						if recv != nil {
+							selected[recv] = true
+						}
+					}
+					if blocked {
+						finding := pass.NewFinding(n, nil, "goroutine blocks forever in a select whose channels are never sent to or closed")
+						finding.Node = "select"
+						pass.ReportFinding(finding)
+					}
+				case *ast.UnaryExpr:
+					if n.Op == token.ARROW && !selected[n] && dead(n.X) {
+						pass.Reportf(n.X, nil, "goroutine blocks forever receiving from a channel that is never sent to or closed")
+					}
+				case *ast.RangeStmt:
+					if dead(n.X) {
+						pass.Reportf(n.X, nil, "goroutine blocks forever ranging over a channel that is never sent to or closed")
+					}
+				}
+				return true
+			})
+		})
+	},
+}
+
+// commRecv returns the receive expression of a select case, or nil for a
+// send or default case.
+func commRecv(clause *ast.CommClause) *ast.UnaryExpr {
+	var expr ast.Expr
+	switch comm := clause.Comm.(type) {
+	case *ast.ExprStmt:
+		expr = comm.X
+	case *ast.AssignStmt:
+		if len(comm.Rhs) == 1 {
+			expr = comm.Rhs[0]
+		}
+	}
+	if recv, ok := ast.Unparen(expr).(*ast.UnaryExpr); ok && recv.Op == token.ARROW {
+		return recv
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// asyncCallbacks lists the functions, by the full name types.Func.FullName
+// gives them, and the func types, by qualified name, whose function
+// arguments are called later, on another goroutine. Config.AsyncCallbacks
+// adds to the list.
+var asyncCallbacks = []string{
+	"time.AfterFunc",
+	"context.AfterFunc",
+	"sync.OnceFunc",
+	"sync.OnceValue",
+	"sync.OnceValues",
+	"net/http.HandleFunc",
+	"(*net/http.ServeMux).HandleFunc",
+	"net/http.HandlerFunc",
+}
+
+var asyncCallback = &Check{
+	Name: "async-callback",
+	ID:   "TS0019",
+	Doc:  "report closures and method values handed to functions that call them later on another goroutine",
+	Rationale: `Functions such as time.AfterFunc and context.AfterFunc, and HTTP handlers,
+run the function they are given on a goroutine of their own, just as a go
+statement would. A closure passed to them shares the variables it captures,
+and a method value its receiver, with the code that registered it. The list
+of such functions is configurable with Config.AsyncCallbacks.`,
+	Bad: `count := 0
+time.AfterFunc(time.Second, func() {
+	count++ // races with the read below
+})
+fmt.Println(count)`,
+	Good: `done := make(chan int)
+time.AfterFunc(time.Second, func() {
+	done <- 1
+})
+fmt.Println(<-done)`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+			call := n.(*ast.CallExpr)
+			if !pass.isAsyncCallback(call) {
+				return
+			}
+			for _, arg := range call.Args {
+				switch arg := ast.Unparen(arg).(type) {
+				case *ast.FuncLit:
+					checkCaptures(pass, call, arg, "callback", "its registrant")
+				case *ast.SelectorExpr:
+					if selection, ok := pass.TypesInfo.Selections[arg]; !ok || selection.Kind() != types.MethodVal {
+						break
+					}
+					recv := pass.TypesInfo.TypeOf(arg.X)
+					if addressedReceiver(pass.TypesInfo, arg) && !pass.isSafeType(types.NewPointer(recv)) {
+						pass.Reportf(arg.X, types.NewPointer(recv), "registering callback on a method with a pointer receiver")
+					} else if contains, pointerType := pass.ContainsPointer(recv); contains {
+						pass.Reportf(arg.X, pointerType, "registering callback on a method of a pointer type")
+					}
+				}
+			}
+		})
+	},
+}
+
+// isAsyncCallback reports whether call is a call of one of the configured
+// async callback functions or a conversion to one of their func types.
+func (p *Pass) isAsyncCallback(call *ast.CallExpr) bool {
+	if tv, ok := p.TypesInfo.Types[call.Fun]; ok && tv.IsType() {
+		named, ok := types.Unalias(tv.Type).(*types.Named)
+		return ok && named.Obj().Pkg() != nil && p.asyncCallbacks[named.Obj().Pkg().Path()+"."+named.Obj().Name()]
+	}
+	callee := typeutil.StaticCallee(p.TypesInfo, call)
+	return callee != nil && p.asyncCallbacks[callee.Origin().FullName()]
+}
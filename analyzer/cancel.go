@@ -0,0 +1,231 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+var uncancellableLoop = &Check{
+	Name:  "uncancellable-loop",
+	ID:    "TS0029",
+	Group: resourceLeakGroup,
+	Doc:   "report long-running loops in goroutines that have a context but never check whether it is done",
+	Rationale: `A goroutine looping forever over a select, or ranging over a channel, runs
+until the program exits unless something tells it to stop. When a
+context.Context is in scope, the loop should receive from ctx.Done() or
+check ctx.Err(), or cancelling the context leaves the goroutine behind.`,
+	Bad: `go func() {
+	for {
+		select {
+		case job := <-jobs:
+			run(ctx, job)
+		}
+	}
+}()`,
+	Good: `go func() {
+	for {
+		select {
+		case job := <-jobs:
+			run(ctx, job)
+		case <-ctx.Done():
+			return
+		}
+	}
+}()`,
+	Run: func(pass *Pass) {
+		// Functions declared in the package and started by go statements
+		// run on goroutines of their own, as function literals do.
+		started := map[*types.Func]bool{}
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil)}, func(n ast.Node) {
+			if fn, ok := calledFunc(pass.TypesInfo, n.(*ast.GoStmt).Call); ok {
+				started[fn] = true
+			}
+		})
+		check := func(body *ast.BlockStmt) {
+			ast.Inspect(body, func(n ast.Node) bool {
+				var loopBody *ast.BlockStmt
+				switch n := n.(type) {
+				case *ast.FuncLit:
+					return false
+				case *ast.ForStmt:
+					if n.Cond == nil && containsSelect(n.Body) {
+						loopBody = n.Body
+					}
+				case *ast.RangeStmt:
+					if isChan(pass.TypesInfo.TypeOf(n.X)) {
+						loopBody = n.Body
+					}
+				}
+				if loopBody == nil || !contextInScope(pass, n.Pos()) || checksDone(pass.TypesInfo, loopBody) {
+					return true
+				}
+				finding := pass.NewFinding(n, nil, "goroutine loop never checks whether its context is done")
+				finding.Node = loopHeader(pass, n)
+				pass.ReportFinding(finding)
+				return false
+			})
+		}
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil), (*ast.FuncDecl)(nil)}, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.GoStmt:
+				if lit, ok := ast.Unparen(n.Call.Fun).(*ast.FuncLit); ok {
+					check(lit.Body)
+				}
+			case *ast.FuncDecl:
+				if fn, ok := pass.TypesInfo.Defs[n.Name].(*types.Func); ok && started[fn] && n.Body != nil {
+					check(n.Body)
+				}
+			}
+		})
+	},
+}
+
+// calledFunc returns the declared function or method call calls, in its
+// generic origin, if it calls one directly.
+func calledFunc(info *types.Info, call *ast.CallExpr) (*types.Func, bool) {
+	var id *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil, false
+	}
+	fn, ok := info.Uses[id].(*types.Func)
+	if !ok {
+		return nil, false
+	}
+	return fn.Origin(), true
+}
+
+// containsSelect reports whether body contains a select statement outside
+// function literals.
+func containsSelect(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.SelectStmt:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// contextInScope reports whether a variable of type context.Context is in
+// scope at pos.
+func contextInScope(pass *Pass, pos token.Pos) bool {
+	for scope := pass.Pkg.Scope().Innermost(pos); scope != nil && scope != pass.Pkg.Scope(); scope = scope.Parent() {
+		for _, name := range scope.Names() {
+			if v, ok := scope.Lookup(name).(*types.Var); ok && v.Pos() < pos && isContext(v.Type()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checksDone reports whether body receives from the Done channel of a
+// context or calls its Err method.
+func checksDone(info *types.Info, body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok && (sel.Sel.Name == "Done" || sel.Sel.Name == "Err") && isContext(info.TypeOf(sel.X)) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// isContext reports whether t is context.Context.
+func isContext(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "context" && named.Obj().Name() == "Context"
+}
+
+var ignoredContext = &Check{
+	Name:     "ignored-context",
+	ID:       "TS0030",
+	Doc:      "note goroutines started by a function taking a context that neither receive nor capture one",
+	Severity: SeverityInfo,
+	Rationale: `A function taking a context.Context promises to stop work when its caller
+cancels it or its deadline passes. A goroutine it starts without passing the
+context along, or capturing it, cannot honor that promise and may outlive
+the request it was started for. Functions of other packages that take no
+context, and builtins, are exempt, having no way to receive one.`,
+	Bad: `func (s *Server) Handle(ctx context.Context, req *Request) {
+	go s.audit(req)
+}`,
+	Good: `func (s *Server) Handle(ctx context.Context, req *Request) {
+	go s.audit(ctx, req)
+}`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil)}, func(n ast.Node) {
+			stmt := n.(*ast.GoStmt)
+			if !hasContextParam(pass, enclosingPath(pass, stmt)) || usesContext(pass.TypesInfo, stmt.Call) {
+				return
+			}
+			if fn, ok := calledFunc(pass.TypesInfo, stmt.Call); ok && fn.Pkg() != pass.Pkg && !takesContext(fn.Type().(*types.Signature)) {
+				return
+			}
+			if id, ok := ast.Unparen(stmt.Call.Fun).(*ast.Ident); ok {
+				if _, ok := pass.TypesInfo.Uses[id].(*types.Builtin); ok {
+					return
+				}
+			}
+			reportGoStmt(pass, stmt, "goroutine neither receives nor captures the context of the function starting it")
+		})
+	},
+}
+
+// hasContextParam reports whether the innermost function in path takes a
+// context.Context parameter.
+func hasContextParam(pass *Pass, path []ast.Node) bool {
+	var typ *ast.FuncType
+	switch fn := funcOf(path).(type) {
+	case *ast.FuncLit:
+		typ = fn.Type
+	case *ast.FuncDecl:
+		typ = fn.Type
+	default:
+		return false
+	}
+	for _, field := range typ.Params.List {
+		if isContext(pass.TypesInfo.TypeOf(field.Type)) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesContext reports whether call passes or refers to a context.Context
+// anywhere, including in a function literal it calls.
+func usesContext(info *types.Info, call *ast.CallExpr) bool {
+	found := false
+	ast.Inspect(call, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			if v, ok := info.Uses[id].(*types.Var); ok && isContext(v.Type()) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// takesContext reports whether sig has a context.Context parameter.
+func takesContext(sig *types.Signature) bool {
+	for i := 0; i < sig.Params().Len(); i++ {
+		if isContext(sig.Params().At(i).Type()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+var chanPointerElem = &Check{
+	Name: "chan-pointer-elem",
+	ID:   "TS0017",
+	Doc:  "report channels made, declared as struct fields or taken as parameters with element types containing pointers",
+	Rationale: `Every value sent on a channel whose element type contains pointers shares
+memory with its receiver. Reporting the channel where it is made or declared
+points at the one place the design can change, where chan-pointer-send
+reports each send separately. Channels annotated //tsgo:transfers are
+exempt.`,
+	Bad: `type Pool struct {
+	jobs chan *Job
+}`,
+	Good: `type Pool struct {
+	jobs chan Job
+}`,
+	Run: func(pass *Pass) {
+		check := func(node ast.Node, t types.Type, what string) {
+			ch, ok := types.Unalias(t).Underlying().(*types.Chan)
+			if !ok {
+				return
+			}
+			if contains, pointerType := pass.ContainsPointer(ch.Elem()); contains {
+				finding := pass.NewFinding(node, pointerType, "%s with element type containing pointers", what)
+				finding.Nested = isNested(ch.Elem(), pointerType)
+				pass.ReportFinding(finding)
+			}
+		}
+		// make calls assigned to channels annotated //tsgo:transfers.
+		transferred := map[*ast.CallExpr]bool{}
+		pass.Inspector.Preorder([]ast.Node{(*ast.AssignStmt)(nil), (*ast.ValueSpec)(nil)}, func(n ast.Node) {
+			var lhs, rhs []ast.Expr
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				lhs, rhs = n.Lhs, n.Rhs
+			case *ast.ValueSpec:
+				for _, id := range n.Names {
+					lhs = append(lhs, id)
+				}
+				rhs = n.Values
+			}
+			if len(lhs) != len(rhs) {
+				return
+			}
+			for i := range lhs {
+				call, ok := ast.Unparen(rhs[i]).(*ast.CallExpr)
+				if !ok {
+					continue
+				}
+				if id, ok := ast.Unparen(lhs[i]).(*ast.Ident); ok && pass.transferChans[pass.TypesInfo.ObjectOf(id)] || pass.transfersOwnership(lhs[i]) {
+					transferred[call] = true
+				}
+			}
+		})
+		pass.Inspector.Preorder([]ast.Node{(*ast.CallExpr)(nil), (*ast.StructType)(nil), (*ast.FuncType)(nil)}, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				if id, ok := ast.Unparen(n.Fun).(*ast.Ident); ok && id.Name == "make" && len(n.Args) > 0 && !transferred[n] {
+					if _, ok := pass.TypesInfo.Uses[id].(*types.Builtin); ok {
+						check(n, pass.TypesInfo.TypeOf(n.Args[0]), "making channel")
+					}
+				}
+			case *ast.StructType:
+				for _, field := range n.Fields.List {
+					if !fieldTransfers(pass, field) {
+						check(field.Type, pass.TypesInfo.TypeOf(field.Type), "channel field")
+					}
+				}
+			case *ast.FuncType:
+				if n.Params == nil {
+					break
+				}
+				for _, field := range n.Params.List {
+					if !fieldTransfers(pass, field) {
+						check(field.Type, pass.TypesInfo.TypeOf(field.Type), "channel parameter")
+					}
+				}
+			}
+		})
+	},
+}
+
+// fieldTransfers reports whether the names declared by field are channels
+// annotated //tsgo:transfers.
+func fieldTransfers(pass *Pass, field *ast.Field) bool {
+	for _, id := range field.Names {
+		if pass.transferChans[pass.TypesInfo.Defs[id]] {
+			return true
+		}
+	}
+	return false
+}
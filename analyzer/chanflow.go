@@ -0,0 +1,296 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// chanFlow records, for each channel variable and struct field of a package,
+// the operations the package performs on it.
+type chanFlow map[*types.Var]*chanOps
+
+// chanOps are the operations performed on a channel variable or field.
+type chanOps struct {
+	// makes are the make calls assigned to it, and nils the declarations
+	// without a value and the assignments of nil.
+	makes []ast.Expr
+	nils  []ast.Node
+	// sends, recvs and closes are its send statements, its receive
+	// expressions and range statements, and the close calls on it, including
+	// calls of functions closing their parameters.
+	sends  []*ast.SendStmt
+	recvs  []ast.Node
+	closes []*ast.CallExpr
+	// escapes is set when the channel is used other than by these
+	// operations: passed to a function, returned, or assigned elsewhere, so
+	// that code the package cannot see may operate on it.
+	escapes bool
+	// addressed is set when the channel's address is taken, so that it may
+	// be assigned through the pointer.
+	addressed bool
+	// foreign is set when the channel may hold one made elsewhere: it is a
+	// parameter, an exported variable or field, or assigned something other
+	// than a make call or nil.
+	foreign bool
+}
+
+// local reports whether every operation on the channel is among those
+// recorded: it holds only channels made by the package, and is used nowhere
+// else.
+func (ops *chanOps) local() bool {
+	return ops != nil && !ops.escapes && !ops.foreign
+}
+
+// of returns the operations recorded for the channel variable or field expr
+// refers to, or nil if it refers to neither.
+func (flow chanFlow) of(info *types.Info, expr ast.Expr) *chanOps {
+	if v := chanVar(info, expr); v != nil {
+		return flow[v]
+	}
+	return nil
+}
+
+// chanVar returns the channel variable or field expr refers to, in its
+// generic origin, or nil.
+func chanVar(info *types.Info, expr ast.Expr) *types.Var {
+	var id *ast.Ident
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		id = e
+	case *ast.SelectorExpr:
+		if _, ok := info.Selections[e]; !ok {
+			return nil
+		}
+		id = e.Sel
+	default:
+		return nil
+	}
+	obj := info.Defs[id]
+	if obj == nil {
+		obj = info.Uses[id]
+	}
+	v, ok := obj.(*types.Var)
+	if !ok {
+		return nil
+	}
+	if !isChan(v.Type()) {
+		return nil
+	}
+	return v.Origin()
+}
+
+// isChan reports whether t is a channel type.
+func isChan(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Chan)
+	return ok
+}
+
+// collectChanFlow returns the operations files perform on their channels,
+// using facts to find the functions that close channels passed to them.
+func collectChanFlow(pass *analysis.Pass, files []*ast.File, facts *factSet) chanFlow {
+	flow := chanFlow{}
+	info := pass.TypesInfo
+	get := func(v *types.Var) *chanOps {
+		ops := flow[v]
+		if ops == nil {
+			ops = &chanOps{}
+			flow[v] = ops
+		}
+		return ops
+	}
+	// declared holds the channels declared by a var declaration, a :=
+	// assignment or a struct type; the others are parameters, results and
+	// range variables.
+	declared := map[*types.Var]bool{}
+	// handled holds the uses of channels by the operations recorded.
+	handled := map[ast.Expr]bool{}
+	assign := func(lhs ast.Expr, rhs ast.Expr, node ast.Node) {
+		v := chanVar(info, lhs)
+		if v == nil {
+			return
+		}
+		handled[ast.Unparen(lhs)] = true
+		ops := get(v)
+		switch {
+		case rhs == nil:
+			ops.nils = append(ops.nils, node)
+		case isNilExpr(info, rhs):
+			ops.nils = append(ops.nils, rhs)
+		case isMakeCall(info, rhs):
+			ops.makes = append(ops.makes, rhs)
+		default:
+			ops.foreign = true
+		}
+	}
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.ValueSpec:
+				for i, id := range n.Names {
+					v := chanVar(info, id)
+					if v == nil {
+						continue
+					}
+					declared[v] = true
+					if id.IsExported() && v.Parent() == v.Pkg().Scope() {
+						get(v).foreign = true
+					}
+					switch {
+					case len(n.Values) == len(n.Names):
+						assign(id, n.Values[i], n)
+					case len(n.Values) == 0:
+						assign(id, nil, n)
+					default:
+						get(v).foreign = true
+					}
+				}
+			case *ast.AssignStmt:
+				for i, lhs := range n.Lhs {
+					v := chanVar(info, lhs)
+					if v == nil {
+						continue
+					}
+					if id, ok := lhs.(*ast.Ident); ok && info.Defs[id] != nil {
+						declared[v] = true
+					}
+					if len(n.Rhs) == len(n.Lhs) {
+						assign(lhs, n.Rhs[i], n)
+					} else {
+						handled[ast.Unparen(lhs)] = true
+						get(v).foreign = true
+					}
+				}
+			case *ast.StructType:
+				for _, field := range n.Fields.List {
+					for _, id := range field.Names {
+						if v := chanVar(info, id); v != nil {
+							declared[v] = true
+							get(v).foreign = get(v).foreign || id.IsExported()
+						}
+					}
+				}
+			case *ast.CompositeLit:
+				st, ok := types.Unalias(info.TypeOf(n)).Underlying().(*types.Struct)
+				if !ok {
+					break
+				}
+				for i, elt := range n.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok {
+						if key, ok := kv.Key.(*ast.Ident); ok && chanVar(info, key) != nil {
+							assign(key, kv.Value, kv)
+						}
+					} else if i < st.NumFields() {
+						if field := st.Field(i); isChan(field.Type()) {
+							get(field.Origin()).foreign = true
+						}
+					}
+				}
+			case *ast.SendStmt:
+				if v := chanVar(info, n.Chan); v != nil {
+					handled[ast.Unparen(n.Chan)] = true
+					get(v).sends = append(get(v).sends, n)
+				}
+			case *ast.UnaryExpr:
+				v := chanVar(info, n.X)
+				if v == nil {
+					break
+				}
+				switch n.Op {
+				case token.ARROW:
+					handled[ast.Unparen(n.X)] = true
+					get(v).recvs = append(get(v).recvs, n)
+				case token.AND:
+					get(v).addressed = true
+				}
+			case *ast.RangeStmt:
+				if v := chanVar(info, n.X); v != nil {
+					handled[ast.Unparen(n.X)] = true
+					get(v).recvs = append(get(v).recvs, n)
+				}
+			case *ast.BinaryExpr:
+				if n.Op == token.EQL || n.Op == token.NEQ {
+					handled[ast.Unparen(n.X)] = true
+					handled[ast.Unparen(n.Y)] = true
+				}
+			case *ast.CallExpr:
+				if callee := typeutil.StaticCallee(info, n); callee != nil {
+					if fact := facts.funcFact(callee); fact != nil {
+						for i, arg := range n.Args {
+							if v := chanVar(info, arg); v != nil && fact.paramClosed(i, callee.Type().(*types.Signature)) {
+								get(v).closes = append(get(v).closes, n)
+							}
+						}
+					}
+				}
+				fun, ok := ast.Unparen(n.Fun).(*ast.Ident)
+				if !ok || len(n.Args) != 1 {
+					break
+				}
+				if _, ok := info.Uses[fun].(*types.Builtin); !ok {
+					break
+				}
+				v := chanVar(info, n.Args[0])
+				if v == nil {
+					break
+				}
+				switch fun.Name {
+				case "close":
+					get(v).closes = append(get(v).closes, n)
+					handled[ast.Unparen(n.Args[0])] = true
+				case "len", "cap":
+					handled[ast.Unparen(n.Args[0])] = true
+				}
+			}
+			return true
+		})
+	}
+	// Any other use lets the channel escape.
+	for _, f := range files {
+		selected := map[*ast.Ident]bool{}
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				if v := chanVar(info, n); v != nil {
+					selected[n.Sel] = true
+					if !handled[n] {
+						get(v).escapes = true
+					}
+				}
+			case *ast.Ident:
+				if v := chanVar(info, n); v != nil && info.Uses[n] != nil && !handled[n] && !selected[n] {
+					get(v).escapes = true
+				}
+			}
+			return true
+		})
+	}
+	for v, ops := range flow {
+		if !declared[v] {
+			ops.foreign = true
+		}
+	}
+	return flow
+}
+
+// isMakeCall reports whether expr calls the make builtin.
+func isMakeCall(info *types.Info, expr ast.Expr) bool {
+	call, ok := ast.Unparen(expr).(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	fun, ok := ast.Unparen(call.Fun).(*ast.Ident)
+	if !ok || fun.Name != "make" {
+		return false
+	}
+	_, ok = info.Uses[fun].(*types.Builtin)
+	return ok
+}
+
+// isNilExpr reports whether expr is the predeclared nil.
+func isNilExpr(info *types.Info, expr ast.Expr) bool {
+	return info.Types[ast.Unparen(expr)].IsNil()
+}
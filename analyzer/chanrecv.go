@@ -0,0 +1,73 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+var chanPointerRecv = &Check{
+	Name: "chan-pointer-recv",
+	ID:   "TS0018",
+	Doc:  "report values containing pointers received from channels, in receive expressions, range loops and select cases",
+	Rationale: `A value received from a channel was sent by another goroutine. If it
+contains a pointer, the sender may still hold the memory it points to, which
+chan-pointer-send cannot see when the sending code is in another package or
+behind an interface.`,
+	Bad: `func consume(jobs <-chan *Job) {
+	for job := range jobs {
+		job.Run() // the producer may still be writing to job
+	}
+}`,
+	Good: `func consume(jobs <-chan Job) {
+	for job := range jobs {
+		job.Run()
+	}
+}`,
+	Run: func(pass *Pass) {
+		discarded := map[ast.Expr]bool{}
+		// selected holds the receives of select cases binding their value.
+		selected := map[ast.Expr]bool{}
+		pass.Inspector.Preorder([]ast.Node{(*ast.CommClause)(nil), (*ast.ExprStmt)(nil), (*ast.UnaryExpr)(nil), (*ast.RangeStmt)(nil)}, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.CommClause:
+				if assign, ok := n.Comm.(*ast.AssignStmt); ok && len(assign.Rhs) == 1 {
+					selected[ast.Unparen(assign.Rhs[0])] = true
+				}
+			case *ast.ExprStmt:
+				// A received value that is thrown away shares nothing.
+				discarded[ast.Unparen(n.X)] = true
+			case *ast.UnaryExpr:
+				if n.Op != token.ARROW || discarded[n] {
+					break
+				}
+				t := pass.TypesInfo.TypeOf(n)
+				if tuple, ok := t.(*types.Tuple); ok {
+					t = tuple.At(0).Type()
+				}
+				if contains, pointerType := pass.ContainsPointer(t); contains && !pass.transfersOwnership(n.X) {
+					where := ""
+					if selected[n] {
+						where = " in a select case"
+					}
+					finding := pass.NewFinding(n, pointerType, "receiving pointer type from a channel%s", where)
+					finding.Nested = isNested(t, pointerType)
+					pass.ReportFinding(finding)
+				}
+			case *ast.RangeStmt:
+				ch, ok := types.Unalias(pass.TypesInfo.TypeOf(n.X)).Underlying().(*types.Chan)
+				if !ok || n.Key == nil || pass.transfersOwnership(n.X) {
+					break
+				}
+				if id, ok := n.Key.(*ast.Ident); ok && id.Name == "_" {
+					break
+				}
+				if contains, pointerType := pass.ContainsPointer(ch.Elem()); contains {
+					finding := pass.NewFinding(n.Key, pointerType, "receiving pointer type from a channel in a range loop")
+					finding.Nested = isNested(ch.Elem(), pointerType)
+					pass.ReportFinding(finding)
+				}
+			}
+		})
+	},
+}
@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+var chanPointerSend = &Check{
+	Name: "chan-pointer-send",
+	ID:   "TS0001",
+	Doc:  "report values containing pointers sent over channels",
+	Rationale: `A value sent over a channel is received by another goroutine. If it
+contains a pointer, both goroutines can reach the memory it points to, and
+any later write by the sender races with the receiver's reads unless the two
+synchronize some other way. A function sent over a channel shares what it
+refers to: the variables a closure captures, or a method value's receiver.`,
+	Bad: `func produce(ch chan *Point) {
+	p := &Point{}
+	ch <- p
+	p.X = 1 // races with the receiver
+}`,
+	Good: `func produce(ch chan Point) {
+	p := Point{}
+	ch <- p // the receiver gets its own copy
+	p.X = 1
+}`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.SendStmt)(nil)}, func(n ast.Node) {
+			send := n.(*ast.SendStmt)
+			if pass.transfersOwnership(send.Chan) || pass.isFresh(send.Value) {
+				// use-after-transfer checks the sender of transfers instead.
+				return
+			}
+			if checkFuncPayload(pass, send.Value) {
+				return
+			}
+			payload := pass.TypesInfo.TypeOf(send.Value)
+			if contains, pointerType := pass.ContainsPointer(payload); contains {
+				finding := pass.NewFinding(send, pointerType, "sending pointer type over a channel%s", cloneHint(payload))
+				finding.Nested = isNested(payload, pointerType)
+				if fix := copySendFix(pass, send); fix != nil {
+					finding.Fixes = append(finding.Fixes, *fix)
+				}
+				pass.ReportFinding(finding)
+			}
+		})
+	},
+}
+
+// checkFuncPayload reports what a function sent over a channel shares with
+// its sender, and returns whether value is a function it could look into: a
+// function literal shares the variables it captures, a method value its
+// receiver, and a declared function nothing.
+func checkFuncPayload(pass *Pass, value ast.Expr) bool {
+	switch value := ast.Unparen(value).(type) {
+	case *ast.FuncLit:
+		for _, id := range capturedVars(pass.TypesInfo, value) {
+			if pass.isFresh(id) {
+				continue
+			}
+			if contains, pointerType := pass.ContainsPointer(pass.TypesInfo.TypeOf(id)); contains {
+				pass.Reportf(id, pointerType, "sending closure over a channel that captures pointer type")
+			}
+		}
+		return true
+	case *ast.SelectorExpr:
+		if selection, ok := pass.TypesInfo.Selections[value]; ok && selection.Kind() == types.MethodVal {
+			recv := pass.TypesInfo.TypeOf(value.X)
+			if addressedReceiver(pass.TypesInfo, value) && !pass.isSafeType(types.NewPointer(recv)) {
+				pass.Reportf(value.X, types.NewPointer(recv), "sending method value with a pointer receiver over a channel")
+			} else if contains, pointerType := pass.ContainsPointer(recv); contains {
+				pass.Reportf(value.X, pointerType, "sending method value of a pointer type over a channel")
+			}
+			return true
+		}
+		_, ok := pass.TypesInfo.Uses[value.Sel].(*types.Func)
+		return ok
+	case *ast.Ident:
+		_, ok := pass.TypesInfo.Uses[value].(*types.Func)
+		return ok
+	}
+	return false
+}
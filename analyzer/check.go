@@ -0,0 +1,378 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// A Check is a single rule that can be enabled, disabled and run on its own.
+type Check struct {
+	// Name identifies the check in configuration and in the Category of the
+	// diagnostics it reports, e.g. "chan-pointer-send".
+	Name string
+	// ID is a short code that stays the same even if the check is renamed,
+	// e.g. "TS0001". Built-in checks use the TS prefix; it may be empty for
+	// custom checks. Configuration accepts either the ID or the name.
+	ID string
+	// Doc is a one-line description of what the check reports.
+	Doc string
+	// Group names the family of related checks the check belongs to, such
+	// as "resource-leak". Configuration accepts a group name wherever it
+	// accepts a check, standing for all the checks of the group.
+	Group string
+	// Severity is the severity of the check's findings unless configured
+	// otherwise. The zero value means SeverityWarning.
+	Severity Severity
+	// OffByDefault checks only run when configuration names them, because
+	// they report code that is often fine; presets such as strict enable
+	// them.
+	OffByDefault bool
+	// Rationale explains why the reported code is a problem, Bad is an
+	// example of it and Good the recommended alternative. tsgo explain
+	// prints them; they are optional for custom checks.
+	Rationale string
+	Bad, Good string
+	Run       func(*Pass)
+}
+
+// Severity classifies how serious a finding is.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Validate returns an error unless s is one of the defined severities.
+func (s Severity) Validate() error {
+	switch s {
+	case SeverityError, SeverityWarning, SeverityInfo:
+		return nil
+	}
+	return fmt.Errorf("invalid severity %q (want error, warning or info)", string(s))
+}
+
+// DefaultSeverity returns c.Severity, or SeverityWarning if it is unset.
+func (c *Check) DefaultSeverity() Severity {
+	if c.Severity == "" {
+		return SeverityWarning
+	}
+	return c.Severity
+}
+
+var (
+	checksMu sync.Mutex
+	// checks lists the registered checks in the order they run, built-in
+	// checks first.
+	checks = []*Check{
+		chanPointerSend,
+		chanPointerElem,
+		chanPointerRecv,
+		syncValueSend,
+		lockCopy,
+		lockByValue,
+		waitGroupByValue,
+		addInGoroutine,
+		goPointerCall,
+		goPointerArg,
+		globalVar,
+		escapingCall,
+		loopVarCapture,
+		suppressionCheck,
+		interfacePayload,
+		readOnlySharing,
+		guardedBy,
+		missingUnlock,
+		undeferredUnlock,
+		recursiveLock,
+		rwMutexMisuse,
+		lockAcrossChan,
+		lockAcrossBlocking,
+		lockOrder,
+		mixedAtomic,
+		useAfterTransfer,
+		sharedDBHandle,
+		sharedRand,
+		sharedBuffer,
+		sharedTemplate,
+		asyncCallback,
+		unboundedSpawn,
+		unjoinedGoroutine,
+		discardedGoResult,
+		initGoroutine,
+		goroutineExit,
+		goroutinePanic,
+		testGoroutineFatal,
+		parallelSubtestCapture,
+		parallelTestEnv,
+		blockedGoroutine,
+		doubleClose,
+		receiverClose,
+		sendCloseRace,
+		nilChannel,
+		unclosedRange,
+		uncancellableLoop,
+		ignoredContext,
+		timeAfterLoop,
+		unstoppedTicker,
+		unbufferedSignal,
+		concurrencyDocMismatch,
+		undocumentedMutex,
+	}
+)
+
+// Register adds a custom check, run alongside the built-in ones by every
+// Analyzer whose Config does not restrict the checks to others. It is meant
+// to be called from an init function of the package defining the check, so
+// that the check is in place before any analysis starts:
+//
+//	func init() {
+//		analyzer.Register(&analyzer.Check{
+//			Name: "app-context-go",
+//			Doc:  "report *AppContext values passed to goroutines",
+//			Run:  runAppContextGo,
+//		})
+//	}
+//
+// A program built around checker.Check, unitchecker.Main(analyzer.Analyzer)
+// or multichecker then reports the custom check's findings with the rest.
+// Register panics if the check has no name or Run function, or if another
+// check is registered under the same name or ID.
+func Register(check *Check) {
+	if check.Name == "" || strings.ContainsAny(check.Name, ", \t") {
+		panic(fmt.Sprintf("tsgo: invalid check name %q", check.Name))
+	}
+	if check.Run == nil {
+		panic(fmt.Sprintf("tsgo: check %s has no Run function", check.Name))
+	}
+	checksMu.Lock()
+	defer checksMu.Unlock()
+	for _, existing := range checks {
+		if existing.Name == check.Name {
+			panic(fmt.Sprintf("tsgo: check %s registered twice", check.Name))
+		}
+		if check.ID != "" && (existing.ID == check.ID || existing.Name == check.ID) {
+			panic(fmt.Sprintf("tsgo: check %s reuses ID %s", check.Name, check.ID))
+		}
+	}
+	checks = append(checks, check)
+}
+
+// Lookup returns the registered check with the given name or ID, or nil.
+func Lookup(nameOrID string) *Check {
+	for _, check := range Checks() {
+		if check.Name == nameOrID || check.ID != "" && check.ID == nameOrID {
+			return check
+		}
+	}
+	return nil
+}
+
+// Resolve returns the check with the given name or ID or, failing that, the
+// checks of the group with that name, or nil if there are none.
+func Resolve(key string) []*Check {
+	if check := Lookup(key); check != nil {
+		return []*Check{check}
+	}
+	var group []*Check
+	for _, check := range Checks() {
+		if check.Group != "" && check.Group == key {
+			group = append(group, check)
+		}
+	}
+	return group
+}
+
+// Checks returns the registered checks, built-in checks first.
+func Checks() []*Check {
+	checksMu.Lock()
+	defer checksMu.Unlock()
+	return append([]*Check(nil), checks...)
+}
+
+// Pass is the information handed to a Check for one package. Files excludes
+// the support files generated by cgo, and Inspector walks only those files.
+type Pass struct {
+	*analysis.Pass
+	Check     *Check
+	Files     []*ast.File
+	Inspector *inspector.Inspector
+
+	facts     *factSet
+	findings  *[]*Finding
+	safeTypes *typeSet
+
+	suppressions       []*suppression
+	strictSuppressions bool
+	// transferChans holds the channels annotated //tsgo:transfers.
+	transferChans map[types.Object]bool
+	// sanitizers and freshVars describe the configured sanitizer functions
+	// and the variables holding their results.
+	sanitizers map[string]bool
+	freshVars  map[*types.Var]bool
+	// asyncCallbacks holds the functions and func types whose function
+	// arguments are called later on another goroutine.
+	asyncCallbacks map[string]bool
+	// spawnWrappers holds the functions starting goroutines on behalf of
+	// their callers.
+	spawnWrappers map[string]bool
+	// blockingFuncs holds the functions that may block for a long time.
+	blockingFuncs map[string]bool
+	// chanFlow records the operations on the package's channels.
+	chanFlow chanFlow
+	// unsafeTypeSets holds the types each category check of unsafeTypes
+	// reports.
+	unsafeTypeSets map[string]*typeSet
+	// minCriticalSection is the size of the critical sections, in
+	// statements, small enough not to need a deferred Unlock.
+	minCriticalSection int
+	// severities holds the configured severities of checks, by name, and
+	// nestedSeverity that of nested findings; severities is nil unless the
+	// configuration sets either, and messages are then prefixed with them.
+	severities     map[string]Severity
+	nestedSeverity Severity
+}
+
+// ContainsPointer reports whether values of type t contain pointers through
+// which goroutines could share memory, and returns the type of the first such
+// pointer. Types configured or annotated as safe to share are not descended
+// into.
+func (p *Pass) ContainsPointer(t types.Type) (bool, types.Type) {
+	return typeContainsPointer(t, p.isSafeType)
+}
+
+// isSafeType reports whether t is one of the types configured as safe to
+// share or annotated as such with //tsgo:threadsafe.
+func (p *Pass) isSafeType(t types.Type) bool {
+	return p.safeTypes.contains(t) || p.facts.threadSafe(t)
+}
+
+// A Finding is a diagnostic reported by a Check, retaining the structure that
+// is flattened into the message of the corresponding analysis.Diagnostic.
+type Finding struct {
+	Check    *Check
+	Pos, End token.Pos
+	// Message describes the problem without the annotation appended for
+	// analysis drivers.
+	Message string
+	// Type is the type most responsible for the finding, if any.
+	Type types.Type
+	// Node is the source text of the offending node.
+	Node string
+	// Nested is set when the pointer is inside a struct or array value
+	// rather than being the value itself, a pattern some configurations
+	// treat as less serious.
+	Nested bool
+	// Fixes are machine-applicable edits resolving the finding, if any.
+	Fixes []Fix
+	// Related are other places involved in the finding, such as the first
+	// close of a channel closed twice. Positions are kept out of Message so
+	// that it stays the same when code above the finding moves.
+	Related []analysis.RelatedInformation
+	// StableMessage, if set, is Message without the positions of other
+	// places that Message names anyway, for findings whose one-line output
+	// must point at them; baselines match findings by it instead.
+	StableMessage string
+}
+
+// Relate adds a related location at node to the finding.
+func (f *Finding) Relate(node ast.Node, format string, args ...interface{}) {
+	f.Related = append(f.Related, analysis.RelatedInformation{Pos: node.Pos(), End: node.End(), Message: fmt.Sprintf(format, args...)})
+}
+
+// Fix is a suggested fix for a finding. A Safe fix only removes the sharing
+// reported and otherwise preserves the program's behaviour, so tools may apply
+// it without review; other fixes change semantics in ways a person should
+// check first.
+type Fix struct {
+	analysis.SuggestedFix
+	Safe bool
+}
+
+// Reportf reports a finding of the running check at node. The type most
+// responsible for the finding is appended to the message, or the source of
+// node when t is nil.
+func (p *Pass) Reportf(node ast.Node, t types.Type, format string, args ...interface{}) {
+	p.ReportFinding(p.NewFinding(node, t, format, args...))
+}
+
+// NewFinding returns a finding of the running check at node without reporting
+// it, so that the caller can attach Fixes before passing it to ReportFinding.
+func (p *Pass) NewFinding(node ast.Node, t types.Type, format string, args ...interface{}) *Finding {
+	return &Finding{
+		Check:   p.Check,
+		Pos:     node.Pos(),
+		End:     node.End(),
+		Message: fmt.Sprintf(format, args...),
+		Type:    t,
+		Node:    stringifyNode(p.Fset, node),
+	}
+}
+
+// ReportFinding reports finding, along with any suggested fixes, both as an
+// analysis.Diagnostic and in the analyzer's result, unless a //tsgo:ignore
+// directive suppresses it.
+func (p *Pass) ReportFinding(finding *Finding) {
+	if p.suppressed(finding) {
+		return
+	}
+	*p.findings = append(*p.findings, finding)
+	var fixes []analysis.SuggestedFix
+	for _, fix := range finding.Fixes {
+		fixes = append(fixes, fix.SuggestedFix)
+	}
+	message := finding.String()
+	if p.severities != nil {
+		message = fmt.Sprintf("%s: %s", p.severityOf(finding), message)
+	}
+	p.Report(analysis.Diagnostic{
+		Pos:            finding.Pos,
+		End:            finding.End,
+		Category:       finding.Check.Name,
+		Message:        message,
+		SuggestedFixes: fixes,
+		Related:        finding.Related,
+	})
+}
+
+// severityOf returns the configured severity of finding.
+func (p *Pass) severityOf(finding *Finding) Severity {
+	if severity, ok := p.severities[finding.Check.Name]; ok {
+		return severity
+	}
+	if finding.Nested && p.nestedSeverity != "" {
+		return p.nestedSeverity
+	}
+	return finding.Check.DefaultSeverity()
+}
+
+// String returns the message followed by the offending type or, lacking one,
+// the offending source.
+func (f *Finding) String() string {
+	var annotation interface{}
+	if f.Type != nil {
+		annotation = f.Type
+	} else {
+		annotation = f.Node
+	}
+	return fmt.Sprintf("%s (%v)", f.Message, annotation)
+}
+
+func stringifyNode(fset *token.FileSet, node ast.Node) string {
+	buffer := bytes.Buffer{}
+	err := printer.Fprint(&buffer, fset, node)
+	if err != nil {
+		panic(err)
+	}
+	return buffer.String()
+}
@@ -0,0 +1,456 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+var doubleClose = &Check{
+	Name: "double-close",
+	ID:   "TS0024",
+	Doc:  "report channels that may be closed more than once",
+	Rationale: `Closing a closed channel panics. A channel closed at several places, in a
+loop, or by a function that may run more than once can be closed twice
+unless the paths to the closes exclude each other: different branches of an
+if or switch, or a return right after the first close. Calls of functions
+that close a channel passed to them count as closes. Closes made through
+sync.Once.Do are exempt.`,
+	Bad: `func (w *Worker) Stop() {
+	close(w.done)
+}
+
+func (w *Worker) fail() {
+	close(w.done) // panics if Stop was called
+}`,
+	Good: `func (w *Worker) Stop() {
+	w.stopOnce.Do(func() { close(w.done) })
+}
+
+func (w *Worker) fail() {
+	w.stopOnce.Do(func() { close(w.done) })
+}`,
+	Run: func(pass *Pass) {
+		for _, ops := range pass.chanFlow {
+			type closeSite struct {
+				call *ast.CallExpr
+				path []ast.Node
+			}
+			var sites []closeSite
+			for _, call := range ops.closes {
+				path := enclosingPath(pass, call)
+				if !closedOnce(path) {
+					sites = append(sites, closeSite{call, path})
+				}
+			}
+			for i, site := range sites {
+				if closingLoop(site.path) != nil {
+					pass.Reportf(site.call, nil, "closing a channel in a loop may close it more than once")
+					continue
+				}
+				for _, earlier := range sites[:i] {
+					if !exclusiveCloses(earlier.path, site.path) {
+						finding := pass.NewFinding(site.call, nil, "channel may be closed more than once; it is also closed elsewhere")
+						finding.Relate(earlier.call, "also closed here")
+						pass.ReportFinding(finding)
+						break
+					}
+				}
+			}
+		}
+	},
+}
+
+// closedOnce reports whether the close at the head of path is made by a
+// function literal passed to a Do method, as of sync.Once.
+func closedOnce(path []ast.Node) bool {
+	for i, n := range path {
+		if _, ok := n.(*ast.FuncLit); !ok || i+1 >= len(path) {
+			continue
+		}
+		if call, ok := path[i+1].(*ast.CallExpr); ok {
+			if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok && sel.Sel.Name == "Do" {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// closingLoop returns the loop, within the function making it, that repeats
+// the close at the head of path without leaving the loop after it, or nil.
+func closingLoop(path []ast.Node) ast.Node {
+	for _, n := range path {
+		switch n := n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			if !exitsAfter(path, n, n.End(), true) {
+				return n
+			}
+			return nil
+		case *ast.FuncLit, *ast.FuncDecl:
+			return nil
+		}
+	}
+	return nil
+}
+
+// exclusiveCloses reports whether the closes at the heads of first and
+// second, in that order in the source, cannot both run: they are in the same
+// function, neither is deferred, and they are in different branches of an if,
+// switch or select, or the function returns after the first before reaching
+// the second.
+func exclusiveCloses(first, second []ast.Node) bool {
+	if funcOf(first) != funcOf(second) || funcOf(first) == nil || deferred(first) || deferred(second) {
+		return false
+	}
+	end := second[0].Pos()
+	for i, n := range first[1:] {
+		if n.Pos() > end || end >= n.End() {
+			continue
+		}
+		// n is the innermost node enclosing both.
+		switch n := n.(type) {
+		case *ast.IfStmt:
+			if first[i] == n.Body && n.Else != nil && n.Else.Pos() <= end {
+				return true
+			}
+		case *ast.BlockStmt:
+			if i+2 < len(first) {
+				switch first[i+2].(type) {
+				case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+					return true
+				}
+			}
+		}
+		return exitsAfter(first, n, end, false)
+	}
+	return false
+}
+
+// exitsAfter reports whether, on the way from the head of path out to stop,
+// some statement list runs a statement leaving stop after the head and
+// before the position before: a return, a panic or a goto, or, with loop set,
+// a break out of stop.
+func exitsAfter(path []ast.Node, stop ast.Node, before token.Pos, loop bool) bool {
+	child := path[0]
+	breakable := true
+	for _, n := range path[1:] {
+		var list []ast.Stmt
+		switch n := n.(type) {
+		case *ast.BlockStmt:
+			list = n.List
+		case *ast.CaseClause:
+			list = n.Body
+		case *ast.CommClause:
+			list = n.Body
+		case *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			breakable = false
+		}
+		for _, stmt := range list {
+			if stmt.Pos() <= child.Pos() || stmt.Pos() >= before {
+				continue
+			}
+			switch stmt := stmt.(type) {
+			case *ast.ReturnStmt:
+				return true
+			case *ast.ExprStmt:
+				if call, ok := stmt.X.(*ast.CallExpr); ok {
+					if fun, ok := ast.Unparen(call.Fun).(*ast.Ident); ok && fun.Name == "panic" {
+						return true
+					}
+				}
+			case *ast.BranchStmt:
+				if stmt.Tok == token.GOTO || loop && stmt.Tok == token.BREAK && (stmt.Label != nil || breakable) {
+					return true
+				}
+			}
+		}
+		if n == stop {
+			return false
+		}
+		child = n
+	}
+	return false
+}
+
+// funcOf returns the innermost function literal or declaration in path.
+func funcOf(path []ast.Node) ast.Node {
+	for _, n := range path {
+		switch n.(type) {
+		case *ast.FuncLit, *ast.FuncDecl:
+			return n
+		}
+	}
+	return nil
+}
+
+// deferred reports whether the call at the head of path is deferred.
+func deferred(path []ast.Node) bool {
+	if len(path) < 2 {
+		return false
+	}
+	_, ok := path[1].(*ast.DeferStmt)
+	return ok
+}
+
+var receiverClose = &Check{
+	Name: "receiver-close",
+	ID:   "TS0025",
+	Doc:  "report channels closed by a function that only receives from them while others send",
+	Rationale: `Only the sending side knows when no more values will be sent. A receiver
+closing the channel cannot know whether a sender is about to send, and a
+send on a closed channel panics. A receiver wanting the senders to stop
+should signal them on a separate channel or cancel a context instead.
+Functions whose goroutines send on the channel, and closes made after
+waiting for the senders or draining the channel, are exempt.`,
+	Bad: `func (p *Pipeline) consume() {
+	for v := range p.values {
+		if v < 0 {
+			close(p.values) // produce may still send
+		}
+	}
+}`,
+	Good: `func (p *Pipeline) consume() {
+	for v := range p.values {
+		if v < 0 {
+			p.cancel() // produce stops sending and closes p.values
+		}
+	}
+}`,
+	Run: func(pass *Pass) {
+		for _, ops := range pass.chanFlow {
+			if len(ops.sends) == 0 || len(ops.recvs) == 0 {
+				continue
+			}
+			receives, sends := map[ast.Node]bool{}, map[ast.Node]bool{}
+			for _, recv := range ops.recvs {
+				receives[funcOf(enclosingPath(pass, recv))] = true
+			}
+			for _, send := range ops.sends {
+				// Sends in function literals, such as the goroutines a
+				// function starts, are sends of the enclosing functions too.
+				for _, n := range enclosingPath(pass, send) {
+					switch n.(type) {
+					case *ast.FuncLit, *ast.FuncDecl:
+						sends[n] = true
+					}
+				}
+			}
+			for _, call := range ops.closes {
+				path := enclosingPath(pass, call)
+				fn := funcOf(path)
+				if receives[fn] && !sends[fn] && !waitedBefore(pass.TypesInfo, path) {
+					pass.Reportf(call, nil, "channel closed by a function that only receives from it, while others send on it")
+				}
+			}
+		}
+	},
+}
+
+var sendCloseRace = &Check{
+	Name: "send-close-race",
+	ID:   "TS0026",
+	Doc:  "report sends on a channel that another goroutine may close first",
+	Rationale: `A send on a closed channel panics. When one goroutine sends while another
+closes the channel, the close must wait until the senders are done: after
+sync.WaitGroup.Wait, or after receiving their results or a done signal.
+Without any such synchronization between starting the goroutine and closing
+the channel, the close can win the race.`,
+	Bad: `results := make(chan int)
+go func() {
+	results <- compute()
+}()
+close(results) // the goroutine may not have sent yet`,
+	Good: `results := make(chan int)
+var wg sync.WaitGroup
+wg.Add(1)
+go func() {
+	defer wg.Done()
+	results <- compute()
+}()
+go func() {
+	wg.Wait()
+	close(results)
+}()`,
+	Run: func(pass *Pass) {
+		for _, ops := range pass.chanFlow {
+			if len(ops.closes) == 0 {
+				continue
+			}
+			for _, send := range ops.sends {
+				sendScope, sendGo := goroutineOf(enclosingPath(pass, send))
+				for _, call := range ops.closes {
+					path := enclosingPath(pass, call)
+					closeScope, closeGo := goroutineOf(path)
+					if closeScope == sendScope || sendGo == nil && closeGo == nil {
+						continue
+					}
+					start, end := closeScope.Pos(), call.Pos()
+					if sendGo != nil && sendGo.Pos() >= closeScope.Pos() && sendGo.End() <= closeScope.End() {
+						start = sendGo.End()
+					}
+					if deferred(path) {
+						// Deferred closes run when the function returns.
+						end = closeScope.End()
+					}
+					if !waitsBetween(pass.TypesInfo, closeScope, start, end) {
+						finding := pass.NewFinding(send, nil, "send may race with a close of the channel on another goroutine, panicking on a closed channel")
+						finding.Relate(call, "closed here")
+						pass.ReportFinding(finding)
+						break
+					}
+				}
+			}
+		}
+	},
+}
+
+// goroutineOf returns the function running the node at the head of path on
+// its own goroutine: the innermost function literal started by a go
+// statement, along with that statement, or else the declared function.
+func goroutineOf(path []ast.Node) (ast.Node, *ast.GoStmt) {
+	for i, n := range path {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			if i+2 < len(path) {
+				if stmt, ok := path[i+2].(*ast.GoStmt); ok && stmt.Call == path[i+1] {
+					return n, stmt
+				}
+			}
+		case *ast.FuncDecl:
+			return n, nil
+		}
+	}
+	return nil, nil
+}
+
+// waitedBefore reports whether a statement completed before the call at the
+// head of path, in the same function, waits for other goroutines, as
+// waitsBetween has it: a drain of a channel, a receive or a call of a Wait
+// method.
+func waitedBefore(info *types.Info, path []ast.Node) bool {
+	for i := 1; i < len(path); i++ {
+		var stmts []ast.Stmt
+		switch n := path[i].(type) {
+		case *ast.FuncLit, *ast.FuncDecl:
+			return false
+		case *ast.BlockStmt:
+			stmts = n.List
+		case *ast.CaseClause:
+			stmts = n.Body
+		case *ast.CommClause:
+			stmts = n.Body
+		}
+		for _, stmt := range stmts {
+			if stmt.End() > path[i-1].Pos() {
+				break
+			}
+			if waitsBetween(info, stmt, stmt.Pos(), stmt.End()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// waitsBetween reports whether scope, outside the function literals it
+// contains, waits for other goroutines between start and end: by calling a
+// Wait method, or receiving from a channel.
+func waitsBetween(info *types.Info, scope ast.Node, start, end token.Pos) bool {
+	found := false
+	ast.Inspect(scope, func(n ast.Node) bool {
+		if n == nil || found || n.End() <= start || n.Pos() >= end {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return n == scope
+		case *ast.CallExpr:
+			if sel, ok := ast.Unparen(n.Fun).(*ast.SelectorExpr); ok && sel.Sel.Name == "Wait" && n.Pos() >= start {
+				found = true
+			}
+		case *ast.UnaryExpr:
+			found = n.Op == token.ARROW && n.Pos() >= start
+		case *ast.RangeStmt:
+			found = n.Pos() >= start && isChan(info.TypeOf(n.X))
+		case *ast.SelectStmt:
+			found = n.Pos() >= start
+		}
+		return !found
+	})
+	return found
+}
+
+var unclosedRange = &Check{
+	Name:  "unclosed-range",
+	ID:    "TS0028",
+	Group: resourceLeakGroup,
+	Doc:   "report range loops over channels that are never closed",
+	Rationale: `A range loop over a channel only ends when the channel is closed. If
+nothing closes it, the loop, and the goroutine running it, waits forever
+once the senders are done. The finding names a send on the channel, where
+the producer should close it when it has sent everything. Loops leaving by
+return, break or panic, and channels made elsewhere, are exempt.`,
+	Bad: `go func() {
+	for i := range n {
+		jobs <- i
+	}
+}()
+for j := range jobs { // never ends
+	run(j)
+}`,
+	Good: `go func() {
+	defer close(jobs)
+	for i := range n {
+		jobs <- i
+	}
+}()
+for j := range jobs {
+	run(j)
+}`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.RangeStmt)(nil)}, func(n ast.Node) {
+			loop := n.(*ast.RangeStmt)
+			ops := pass.chanFlow.of(pass.TypesInfo, loop.X)
+			if !ops.local() || len(ops.closes) > 0 || len(ops.sends) == 0 || leavesLoop(loop.Body, true) {
+				return
+			}
+			finding := pass.NewFinding(loop.X, nil, "range over a channel that is never closed never ends; close it once every value is sent")
+			finding.Relate(ops.sends[0], "sent here")
+			pass.ReportFinding(finding)
+		})
+	},
+}
+
+// leavesLoop reports whether stmt, within the body of a loop, may leave the
+// loop: by returning, panicking, jumping with goto or a labeled break, or,
+// with direct set since no switch, select or loop intervenes, by an
+// unlabeled break.
+func leavesLoop(stmt ast.Node, direct bool) bool {
+	leaves := false
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		if leaves {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.ReturnStmt:
+			leaves = true
+		case *ast.BranchStmt:
+			leaves = n.Tok == token.GOTO || n.Tok == token.BREAK && (n.Label != nil || direct)
+		case *ast.CallExpr:
+			if fun, ok := ast.Unparen(n.Fun).(*ast.Ident); ok && fun.Name == "panic" {
+				leaves = true
+			}
+		case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt:
+			if n != stmt && direct {
+				leaves = leavesLoop(n, false)
+				return false
+			}
+		}
+		return !leaves
+	})
+	return leaves
+}
@@ -0,0 +1,213 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+var (
+	// concurrencySafeClaim matches documentation claiming a type is safe for
+	// concurrent use; notClaim matches the claim negated.
+	concurrencySafeClaim = regexp.MustCompile(`(?i)\b(safe\s+for\s+(concurrent|simultaneous|parallel)\s+use|safe\s+to\s+use\s+concurrently|goroutine-safe|thread-safe)`)
+	notClaim             = regexp.MustCompile(`(?i)\bnot\s+(safe\s+for\s+(concurrent|simultaneous|parallel)\s+use|safe\s+to\s+use\s+concurrently|goroutine-safe|thread-safe)`)
+	// concurrencyMention matches documentation saying anything about
+	// concurrent use.
+	concurrencyMention = regexp.MustCompile(`(?i)concurren|goroutine|thread|parallel|synchroni[sz]|simultaneous|\block`)
+)
+
+var concurrencyDocMismatch = &Check{
+	Name: "concurrency-doc-mismatch",
+	ID:   "TS0015",
+	Doc:  "report methods mutating a type documented as safe for concurrent use without synchronizing",
+	Rationale: `A type documented as safe for concurrent use by multiple goroutines, or
+annotated //tsgo:threadsafe, is shared freely on the strength of that claim.
+An exported method writing to its receiver's fields without taking a lock,
+using sync/atomic or communicating over a channel breaks the promise, and
+the pointer checks trust it and stay silent.`,
+	Bad: `// Stats is safe for concurrent use by multiple goroutines.
+type Stats struct {
+	hits int
+}
+
+func (s *Stats) Hit() {
+	s.hits++
+}`,
+	Good: `// Stats is safe for concurrent use by multiple goroutines.
+type Stats struct {
+	hits atomic.Int64
+}
+
+func (s *Stats) Hit() {
+	s.hits.Add(1)
+}`,
+	Run: func(pass *Pass) {
+		claimed := map[*types.TypeName]bool{}
+		for _, spec := range typeSpecs(pass) {
+			text := spec.doc.Text()
+			if concurrencySafeClaim.MatchString(text) && !notClaim.MatchString(text) || hasDirective(spec.doc, threadsafeDirective) {
+				claimed[spec.obj] = true
+			}
+		}
+		if len(claimed) == 0 {
+			return
+		}
+		for _, f := range pass.Files {
+			for _, decl := range f.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Recv == nil || fd.Body == nil || !fd.Name.IsExported() {
+					continue
+				}
+				fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func)
+				if !ok {
+					continue
+				}
+				recv := fn.Type().(*types.Signature).Recv()
+				ptr, ok := types.Unalias(recv.Type()).(*types.Pointer)
+				if !ok {
+					// Methods on values mutate copies.
+					continue
+				}
+				named, ok := types.Unalias(ptr.Elem()).(*types.Named)
+				if !ok || !claimed[named.Origin().Obj()] || synchronizes(pass.TypesInfo, fd.Body) {
+					continue
+				}
+				if write := receiverWrite(pass.TypesInfo, recv, fd.Body); write != nil {
+					pass.Reportf(write, nil, "method %s writes its receiver without synchronizing, but %s is documented as safe for concurrent use", fd.Name.Name, named.Obj().Name())
+				}
+			}
+		}
+	},
+}
+
+var undocumentedMutex = &Check{
+	Name:     "undocumented-mutex",
+	ID:       "TS0016",
+	Doc:      "note exported types containing a mutex whose documentation says nothing about concurrent use",
+	Severity: SeverityInfo,
+	Rationale: `A mutex in a struct suggests the type is meant to be used from several
+goroutines, but callers can only rely on what the documentation promises.
+Saying whether, and how, the type may be used concurrently lets them share
+it without reading its implementation.`,
+	Bad: `// Cache maps keys to values.
+type Cache struct {
+	mu sync.Mutex
+	m  map[string]string
+}`,
+	Good: `// Cache maps keys to values. It is safe for concurrent use by multiple
+// goroutines.
+type Cache struct {
+	mu sync.Mutex
+	m  map[string]string
+}`,
+	Run: func(pass *Pass) {
+		for _, spec := range typeSpecs(pass) {
+			if !spec.obj.Exported() || concurrencyMention.MatchString(spec.doc.Text()) {
+				continue
+			}
+			st, ok := spec.obj.Type().Underlying().(*types.Struct)
+			if !ok {
+				continue
+			}
+			for i := 0; i < st.NumFields(); i++ {
+				if isMutex(st.Field(i).Type()) {
+					pass.Reportf(spec.name, nil, "type contains a mutex but its documentation does not say whether it is safe for concurrent use")
+					break
+				}
+			}
+		}
+	},
+}
+
+// A typeSpec is a type declared by the package with its documentation.
+type typeSpec struct {
+	name *ast.Ident
+	obj  *types.TypeName
+	doc  *ast.CommentGroup
+}
+
+// typeSpecs returns the package-level type declarations of the package.
+func typeSpecs(pass *Pass) []typeSpec {
+	var specs []typeSpec
+	for _, f := range pass.Files {
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if obj, ok := pass.TypesInfo.Defs[ts.Name].(*types.TypeName); ok {
+					specs = append(specs, typeSpec{name: ts.Name, obj: obj, doc: specDoc(gd, ts.Doc)})
+				}
+			}
+		}
+	}
+	return specs
+}
+
+// isMutex reports whether t is sync.Mutex or sync.RWMutex, or a pointer to
+// one.
+func isMutex(t types.Type) bool {
+	if ptr, ok := types.Unalias(t).(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != "sync" {
+		return false
+	}
+	return named.Obj().Name() == "Mutex" || named.Obj().Name() == "RWMutex"
+}
+
+// synchronizes reports whether body calls into sync or sync/atomic, or
+// communicates over a channel, directly or in a function literal.
+func synchronizes(info *types.Info, body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SendStmt:
+			found = true
+		case *ast.UnaryExpr:
+			found = found || n.Op == token.ARROW
+		case *ast.SelectStmt:
+			found = true
+		case *ast.CallExpr:
+			if callee := typeutil.Callee(info, n); callee != nil && callee.Pkg() != nil {
+				path := callee.Pkg().Path()
+				found = found || path == "sync" || path == "sync/atomic"
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// receiverWrite returns the first assignment target in body that writes
+// through recv, such as s.hits or s.m[k], or nil if there is none.
+func receiverWrite(info *types.Info, recv *types.Var, body *ast.BlockStmt) ast.Expr {
+	var write ast.Expr
+	check := func(lhs ast.Expr) {
+		if _, ok := ast.Unparen(lhs).(*ast.Ident); ok || write != nil {
+			// Assigning the receiver variable itself writes nothing shared.
+			return
+		}
+		if rootObject(info, lhs) == recv {
+			write = lhs
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				check(lhs)
+			}
+		case *ast.IncDecStmt:
+			check(n.X)
+		}
+		return write == nil
+	})
+	return write
+}
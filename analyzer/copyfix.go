@@ -0,0 +1,251 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// maxCopySize is the largest struct, in bytes, the copy-before-send fix
+// offers to pass by value instead of by pointer.
+const maxCopySize = 64
+
+// copySendFix returns a fix for a send of a pointer to a small, pointer-free
+// struct: the channel's element type becomes the struct itself and every send
+// on the channel in the package sends a copy of the value instead. The fix is
+// only offered for a local variable, an unexported package variable or an
+// unexported struct field made by the package, whose type nothing outside the
+// package can depend on, and it is unsafe because receivers and other code
+// handling the channel may depend on the pointer.
+func copySendFix(pass *Pass, send *ast.SendStmt) *Fix {
+	valueType := pass.TypesInfo.TypeOf(send.Value)
+	ptr, ok := types.Unalias(valueType).(*types.Pointer)
+	if !ok {
+		return nil
+	}
+	elem := ptr.Elem()
+	if _, ok := elem.Underlying().(*types.Struct); !ok {
+		return nil
+	}
+	if contains, _ := typeContainsPointer(elem, nil); contains || containsLock(elem) || pass.TypesSizes.Sizeof(elem) > maxCopySize {
+		return nil
+	}
+	ch, ok := pass.TypesInfo.TypeOf(send.Chan).Underlying().(*types.Chan)
+	if !ok || !types.Identical(ch.Elem(), valueType) {
+		return nil
+	}
+	obj, ok := referencedVar(pass.TypesInfo, send.Chan)
+	if !ok || obj.Pkg() != pass.Pkg {
+		return nil
+	}
+	if (obj.IsField() || obj.Parent() == pass.Pkg.Scope()) && obj.Exported() {
+		return nil
+	}
+	declarations, ok := chanDeclarations(pass, obj)
+	if !ok {
+		return nil
+	}
+
+	var edits []analysis.TextEdit
+	for _, chanType := range declarations {
+		star, ok := chanType.Value.(*ast.StarExpr)
+		if !ok {
+			return nil
+		}
+		edits = append(edits, analysis.TextEdit{
+			Pos:     star.Pos(),
+			End:     star.End(),
+			NewText: []byte(stringifyNode(pass.Fset, star.X)),
+		})
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+	pass.Inspector.Preorder([]ast.Node{(*ast.SendStmt)(nil)}, func(n ast.Node) {
+		other := n.(*ast.SendStmt)
+		if sent, ok := referencedVar(pass.TypesInfo, other.Chan); !ok || sent != obj {
+			return
+		}
+		edits = append(edits, analysis.TextEdit{
+			Pos:     other.Value.Pos(),
+			End:     other.Value.End(),
+			NewText: []byte(dereference(pass.Fset, other.Value)),
+		})
+	})
+	return &Fix{
+		SuggestedFix: analysis.SuggestedFix{
+			Message:   fmt.Sprintf("send copies of %s instead of pointers", types.TypeString(elem, types.RelativeTo(pass.Pkg))),
+			TextEdits: edits,
+		},
+	}
+}
+
+// dereference returns source for the value value points to: x for &x, and
+// *value otherwise.
+func dereference(fset *token.FileSet, value ast.Expr) string {
+	if addr, ok := ast.Unparen(value).(*ast.UnaryExpr); ok && addr.Op == token.AND {
+		return stringifyNode(fset, addr.X)
+	}
+	switch ast.Unparen(value).(type) {
+	case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr, *ast.CallExpr:
+		return "*" + stringifyNode(fset, value)
+	}
+	return "*(" + stringifyNode(fset, value) + ")"
+}
+
+// referencedVar returns the variable or field expr names, if it is an
+// identifier or a field selector.
+func referencedVar(info *types.Info, expr ast.Expr) (*types.Var, bool) {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		v, ok := info.ObjectOf(e).(*types.Var)
+		return v, ok
+	case *ast.SelectorExpr:
+		if sel, ok := info.Selections[e]; ok && sel.Kind() == types.FieldVal {
+			v, ok := sel.Obj().(*types.Var)
+			return v, ok
+		}
+	}
+	return nil, false
+}
+
+// chanDeclarations returns the channel type expressions that determine the
+// type of obj: its declared type, if spelled out, and the make calls whose
+// results are assigned to it. It reports false if changing them would not
+// change the type of obj alone: when obj is a parameter or result, is
+// assigned anything else, or is passed to a function or returned.
+func chanDeclarations(pass *Pass, obj *types.Var) ([]*ast.ChanType, bool) {
+	info := pass.TypesInfo
+	var found []*ast.ChanType
+	ok := true
+	add := func(expr ast.Expr) {
+		if ct := chanTypeOf(info, expr); ct != nil {
+			found = append(found, ct)
+		} else {
+			ok = false
+		}
+	}
+	refersTo := func(expr ast.Expr) bool {
+		v, ok := referencedVar(info, expr)
+		return ok && v == obj
+	}
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.StructType:
+				for _, field := range n.Fields.List {
+					for _, name := range field.Names {
+						if info.Defs[name] == obj {
+							add(field.Type)
+						}
+					}
+				}
+			case *ast.FuncType:
+				for _, list := range []*ast.FieldList{n.Params, n.Results} {
+					if list == nil {
+						continue
+					}
+					for _, field := range list.List {
+						for _, name := range field.Names {
+							ok = ok && info.Defs[name] != obj
+						}
+					}
+				}
+			case *ast.CallExpr:
+				if _, builtin := info.Uses[calledIdent(n)].(*types.Builtin); !builtin {
+					for _, arg := range n.Args {
+						ok = ok && !refersTo(arg)
+					}
+				}
+			case *ast.ReturnStmt:
+				for _, result := range n.Results {
+					ok = ok && !refersTo(result)
+				}
+			case *ast.ValueSpec:
+				for i, name := range n.Names {
+					if info.Defs[name] != obj {
+						continue
+					}
+					if n.Type != nil {
+						add(n.Type)
+					}
+					if len(n.Values) == len(n.Names) {
+						add(n.Values[i])
+					}
+				}
+			case *ast.AssignStmt:
+				if len(n.Lhs) == len(n.Rhs) {
+					for i, lhs := range n.Lhs {
+						if refersTo(lhs) {
+							add(n.Rhs[i])
+						}
+					}
+				}
+			case *ast.KeyValueExpr:
+				if key, ok := n.Key.(*ast.Ident); ok && info.Uses[key] == obj {
+					add(n.Value)
+				}
+			}
+			return ok
+		})
+	}
+	return found, ok
+}
+
+// calledIdent returns the identifier call calls, or nil if it calls
+// something else, such as a function literal.
+func calledIdent(call *ast.CallExpr) *ast.Ident {
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		return fun
+	case *ast.SelectorExpr:
+		return fun.Sel
+	}
+	return nil
+}
+
+// chanTypeOf returns the channel type spelled out by expr, either directly or
+// as the first argument of a call to the built-in make.
+func chanTypeOf(info *types.Info, expr ast.Expr) *ast.ChanType {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.ChanType:
+		return e
+	case *ast.CallExpr:
+		if id, ok := ast.Unparen(e.Fun).(*ast.Ident); ok && len(e.Args) > 0 {
+			if b, ok := info.Uses[id].(*types.Builtin); ok && b.Name() == "make" {
+				if ct, ok := ast.Unparen(e.Args[0]).(*ast.ChanType); ok {
+					return ct
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// containsLock reports whether values of t contain a lock, such as a
+// sync.Mutex, that must not be copied once used: any struct field whose
+// pointer type has Lock and Unlock methods.
+func containsLock(t types.Type) bool {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		if arr, ok := t.Underlying().(*types.Array); ok {
+			return containsLock(arr.Elem())
+		}
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i).Type()
+		if hasLockMethods(field) || containsLock(field) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLockMethods(t types.Type) bool {
+	mset := types.NewMethodSet(types.NewPointer(t))
+	return mset.Lookup(nil, "Lock") != nil && mset.Lookup(nil, "Unlock") != nil
+}
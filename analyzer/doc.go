@@ -0,0 +1,72 @@
+// Package analyzer exposes tsgo's checks as a golang.org/x/tools/go/analysis
+// Analyzer, so they can run under the tsgo command, multichecker, go vet
+// -vettool or gopls.
+//
+// # Suppressing findings
+//
+// A //tsgo:ignore comment on the offending line, or alone on the line above
+// it, suppresses the findings of the checks it names, by name or ID:
+//
+//	ch <- p //tsgo:ignore chan-pointer-send -- the receiver takes ownership
+//
+// The text after "--" explains why. Config.StrictSuppressions, the -strict
+// flag of the tsgo command, requires both a check and a reason.
+//
+// In the doc comment of a function, //tsgo:ignore covers the whole function,
+// and //tsgo:ignorefile before the package clause covers the whole file.
+// Both silence every check unless they name some.
+//
+// # Annotations
+//
+// A //tsgo:threadsafe line in the doc comment of a type declaration marks the
+// type as safe to share between goroutines, like an entry in
+// Config.SafeTypes. Importing packages see the annotation too.
+//
+// A //tsgo:guardedby mu comment on a struct field or package-level variable
+// names the mutex that must be held to access it, which the guarded-by check
+// verifies.
+//
+// A //tsgo:owns p line in the doc comment of a function says that it takes
+// ownership of its parameter p, and a //tsgo:transfers comment on a channel
+// that sends on it hand the value over. Such transfers are not reported as
+// sharing; the use-after-transfer check instead reports the sender using the
+// value afterwards.
+//
+// A struct field tagged tsgo:"shared" or tsgo:"immutable" is deliberately
+// shared: pointers in it are not reported, while the other fields of the
+// struct are still checked.
+//
+// # Facts
+//
+// Analyzer depends on Facts, which summarizes each function's goroutine
+// behaviour and exports the summaries as object facts. Facts are plain
+// gob-encoded values keyed by the objects they describe, so they survive
+// separate, per-package analysis in which each unit only sees the export data
+// and serialized facts of its direct dependencies.
+//
+// # Bazel
+//
+// The package follows the convention rules_go's nogo expects of analyzer
+// packages: it exports a single Analyzer variable, and Facts is reached
+// through Analyzer.Requires. Register it in the nogo rule:
+//
+//	nogo(
+//	    name = "nogo",
+//	    deps = ["@com_github_rpetrich_tsgo//analyzer"],
+//	    config = "nogo_config.json",
+//	    visibility = ["//visibility:public"],
+//	)
+//
+// nogo runs analyzers over every package in the build, including the
+// standard library and external modules. Restrict findings to your own code
+// in nogo_config.json; facts are still computed for excluded packages:
+//
+//	{
+//	  "tsgo": {
+//	    "exclude_files": {
+//	      "external/": "third-party code",
+//	      "GOROOT/": "standard library"
+//	    }
+//	  }
+//	}
+package analyzer
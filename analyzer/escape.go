@@ -0,0 +1,68 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+var escapingCall = &Check{
+	Name: "escaping-call",
+	ID:   "TS0005",
+	Doc:  "report pointers passed to functions that hand them to another goroutine, using facts across packages",
+	Rationale: `A function that starts a goroutine with one of its arguments, or sends it on
+a channel, shares that argument just as a go statement would. The caller
+usually cannot see this, so it keeps using the value as if it were private.`,
+	Bad: `func startLogging(cfg *Config) {
+	go watch(cfg)
+}
+
+startLogging(cfg)
+cfg.Level = "debug" // races with watch`,
+	Good: `func startLogging(cfg Config) {
+	go watch(&cfg) // watch gets its own copy
+}
+
+startLogging(*cfg)
+cfg.Level = "debug"`,
+	Run: func(pass *Pass) {
+		goCalls := map[*ast.CallExpr]bool{}
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.GoStmt:
+				// The go statement checks already cover the spawned call.
+				goCalls[n.Call] = true
+			case *ast.CallExpr:
+				if !goCalls[n] {
+					checkEscapingCall(pass, n)
+				}
+			}
+		})
+	},
+}
+
+func checkEscapingCall(pass *Pass, call *ast.CallExpr) {
+	callee := typeutil.StaticCallee(pass.TypesInfo, call)
+	if callee == nil {
+		return
+	}
+	fact := pass.facts.funcFact(callee)
+	if fact == nil {
+		return
+	}
+	sig := callee.Type().(*types.Signature)
+	if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok && fact.ReceiverEscapes && sig.Recv() != nil {
+		if contains, pointerType := pass.ContainsPointer(pass.TypesInfo.TypeOf(sel.X)); contains {
+			pass.Reportf(sel.X, pointerType, "calling %s, which shares its receiver with another goroutine, on a pointer type", callee.Name())
+		}
+	}
+	for i, arg := range call.Args {
+		if !fact.paramEscapes(i, sig) || fact.paramOwned(i, sig) || pass.isFresh(arg) {
+			continue
+		}
+		if contains, pointerType := pass.ContainsPointer(pass.TypesInfo.TypeOf(arg)); contains {
+			pass.Reportf(arg, pointerType, "passing pointer type to %s, which shares it with another goroutine%s", callee.Name(), cloneHint(pass.TypesInfo.TypeOf(arg)))
+		}
+	}
+}
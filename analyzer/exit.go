@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"go/ast"
+
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+var goroutineExit = &Check{
+	Name: "goroutine-exit",
+	ID:   "TS0034",
+	Doc:  "report goroutines calling os.Exit or log.Fatal, directly or through a function they call",
+	Rationale: `os.Exit, and log.Fatal which calls it, end the process at once. Called from
+a goroutine other than main's, they cut short every other goroutine without
+running its deferred calls: files are not flushed, locks not released and
+transactions not rolled back. Report the error to the spawner, over a
+channel or through an errgroup, and let main decide to exit.`,
+	Bad: `go func() {
+	if err := serve(); err != nil {
+		log.Fatal(err) // main's deferred cleanup never runs
+	}
+}()`,
+	Good: `errs := make(chan error, 1)
+go func() {
+	errs <- serve()
+}()
+if err := <-errs; err != nil {
+	return err
+}`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil)}, func(n ast.Node) {
+			stmt := n.(*ast.GoStmt)
+			if lit, ok := ast.Unparen(stmt.Call.Fun).(*ast.FuncLit); ok {
+				call := exitsProcess(pass.TypesInfo, lit.Body, pass.facts)
+				if call == nil {
+					return
+				}
+				callee := typeutil.StaticCallee(pass.TypesInfo, call)
+				if exitFuncs[callee.FullName()] {
+					pass.Reportf(call, nil, "%s in a goroutine ends the process without running the deferred calls of other goroutines", callee.FullName())
+				} else {
+					pass.Reportf(call, nil, "goroutine calls %s, which may end the process without running the deferred calls of other goroutines", callee.Name())
+				}
+				return
+			}
+			if callee := typeutil.StaticCallee(pass.TypesInfo, stmt.Call); callee != nil {
+				if fact := pass.facts.funcFact(callee); exitFuncs[callee.FullName()] || fact != nil && fact.ExitsProcess {
+					reportGoStmt(pass, stmt, "goroutine may end the process without running the deferred calls of other goroutines")
+				}
+			}
+		})
+	},
+}
@@ -0,0 +1,396 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// Facts computes, for every function of a package, how it shares its inputs
+// with other goroutines, and exports the summaries as facts so that packages
+// importing it can reason about calls across the import boundary. It also
+// exports the //tsgo:threadsafe annotations of the package's types. Its result
+// is a *factSet covering the package and all of its dependencies.
+var Facts = &analysis.Analyzer{
+	Name:             "tsgofacts",
+	Doc:              "summarize which functions spawn goroutines and which of their inputs escape to them",
+	Run:              runFacts,
+	RunDespiteErrors: true,
+	FactTypes:        []analysis.Fact{new(funcFact), new(typeFact)},
+	ResultType:       reflect.TypeOf(new(factSet)),
+}
+
+// funcFact summarizes how a function shares its inputs with other goroutines.
+type funcFact struct {
+	// SpawnsGoroutine is set when the function starts a goroutine, directly
+	// or through a callee.
+	SpawnsGoroutine bool
+	// ReceiverEscapes and EscapingParams record which inputs may be handed
+	// to another goroutine, by passing them to a go statement, capturing them
+	// in one, sending them on a channel or passing them to a callee that does.
+	ReceiverEscapes bool
+	EscapingParams  []int
+	// OwnedParams lists the parameters a //tsgo:owns directive says the
+	// function takes ownership of.
+	OwnedParams []int
+	// ExitsProcess is set when the function may end the process, by calling
+	// os.Exit or log.Fatal, on its own goroutine, directly or through a
+	// callee.
+	ExitsProcess bool
+	// ClosedParams lists the channel parameters the function closes,
+	// directly or through a callee.
+	ClosedParams []int
+	// LockedFields lists the mutexes a method locks, directly or through a
+	// method of a field, by their path from the receiver: "mu" or
+	// "state.mu", or "" for the receiver itself.
+	LockedFields []string
+}
+
+func (*funcFact) AFact() {}
+
+func (f *funcFact) String() string {
+	return fmt.Sprintf("spawns=%t receiver=%t params=%v owned=%v closed=%v exits=%t locks=%q", f.SpawnsGoroutine, f.ReceiverEscapes, f.EscapingParams, f.OwnedParams, f.ClosedParams, f.ExitsProcess, f.LockedFields)
+}
+
+func (f *funcFact) paramEscapes(i int, sig *types.Signature) bool {
+	return hasParam(f.EscapingParams, i, sig)
+}
+
+func (f *funcFact) paramOwned(i int, sig *types.Signature) bool {
+	return hasParam(f.OwnedParams, i, sig)
+}
+
+func (f *funcFact) paramClosed(i int, sig *types.Signature) bool {
+	return hasParam(f.ClosedParams, i, sig)
+}
+
+// hasParam reports whether params includes the parameter receiving argument
+// i of a call to a function of type sig.
+func hasParam(params []int, i int, sig *types.Signature) bool {
+	if sig.Variadic() && i >= sig.Params().Len()-1 {
+		i = sig.Params().Len() - 1
+	}
+	for _, p := range params {
+		if p == i {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *funcFact) equal(other *funcFact) bool {
+	return f.SpawnsGoroutine == other.SpawnsGoroutine &&
+		f.ExitsProcess == other.ExitsProcess &&
+		f.ReceiverEscapes == other.ReceiverEscapes &&
+		len(f.EscapingParams) == len(other.EscapingParams) &&
+		len(f.OwnedParams) == len(other.OwnedParams) &&
+		len(f.ClosedParams) == len(other.ClosedParams) &&
+		len(f.LockedFields) == len(other.LockedFields)
+}
+
+type factSet struct {
+	funcs map[*types.Func]*funcFact
+	types map[*types.TypeName]*typeFact
+}
+
+func (s *factSet) funcFact(fn *types.Func) *funcFact {
+	return s.funcs[fn.Origin()]
+}
+
+func runFacts(pass *analysis.Pass) (interface{}, error) {
+	facts := &factSet{funcs: map[*types.Func]*funcFact{}, types: map[*types.TypeName]*typeFact{}}
+	for _, imported := range pass.AllObjectFacts() {
+		switch obj := imported.Object.(type) {
+		case *types.Func:
+			facts.funcs[obj] = imported.Fact.(*funcFact)
+		case *types.TypeName:
+			facts.types[obj] = imported.Fact.(*typeFact)
+		}
+	}
+	collectTypeAnnotations(pass, facts)
+
+	type decl struct {
+		fn    *types.Func
+		body  *ast.BlockStmt
+		owned []int
+	}
+	var decls []decl
+	for _, f := range pass.Files {
+		for _, d := range f.Decls {
+			fd, ok := d.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			if fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+				decls = append(decls, decl{fn: fn, body: fd.Body, owned: ownedParams(fd, fn)})
+			}
+		}
+	}
+
+	// Iterate to a fixed point so that recursion and calls between functions
+	// declared in any order are accounted for. Summaries only ever grow.
+	for changed := true; changed; {
+		changed = false
+		for _, d := range decls {
+			fact := summarizeFunc(pass.TypesInfo, d.fn, d.body, facts)
+			fact.OwnedParams = d.owned
+			if old := facts.funcs[d.fn]; old == nil || !old.equal(fact) {
+				facts.funcs[d.fn] = fact
+				changed = true
+			}
+		}
+	}
+
+	for _, d := range decls {
+		if fact := facts.funcs[d.fn]; fact.SpawnsGoroutine || fact.ExitsProcess || fact.ReceiverEscapes || len(fact.EscapingParams) > 0 || len(fact.OwnedParams) > 0 || len(fact.ClosedParams) > 0 || len(fact.LockedFields) > 0 {
+			pass.ExportObjectFact(d.fn, fact)
+		}
+	}
+	return facts, nil
+}
+
+func summarizeFunc(info *types.Info, fn *types.Func, body *ast.BlockStmt, facts *factSet) *funcFact {
+	sig := fn.Type().(*types.Signature)
+	inputs := map[types.Object]int{}
+	if recv := sig.Recv(); recv != nil {
+		inputs[recv] = -1
+	}
+	for i := 0; i < sig.Params().Len(); i++ {
+		inputs[sig.Params().At(i)] = i
+	}
+
+	fact := &funcFact{}
+	escapes := map[int]bool{}
+	closes := map[int]bool{}
+	closeParam := func(expr ast.Expr) {
+		if id, ok := ast.Unparen(expr).(*ast.Ident); ok {
+			if i, ok := inputs[info.Uses[id]]; ok && i >= 0 {
+				closes[i] = true
+			}
+		}
+	}
+	escape := func(expr ast.Expr) {
+		if i, ok := inputs[rootObject(info, expr)]; ok {
+			escapes[i] = true
+		}
+	}
+	escapeCaptured := func(lit *ast.FuncLit) {
+		ast.Inspect(lit.Body, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				if i, ok := inputs[info.Uses[id]]; ok {
+					escapes[i] = true
+				}
+			}
+			return true
+		})
+	}
+
+	fact.ExitsProcess = exitsProcess(info, body, facts) != nil
+	if recv := sig.Recv(); recv != nil {
+		fact.LockedFields = lockedFields(info, recv, body, facts)
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GoStmt:
+			fact.SpawnsGoroutine = true
+			for _, arg := range n.Call.Args {
+				escape(arg)
+			}
+			switch fun := ast.Unparen(n.Call.Fun).(type) {
+			case *ast.FuncLit:
+				escapeCaptured(fun)
+			case *ast.SelectorExpr:
+				escape(fun.X)
+			}
+		case *ast.SendStmt:
+			escape(n.Value)
+		case *ast.CallExpr:
+			if fun, ok := ast.Unparen(n.Fun).(*ast.Ident); ok && fun.Name == "close" && len(n.Args) == 1 {
+				if _, ok := info.Uses[fun].(*types.Builtin); ok {
+					closeParam(n.Args[0])
+				}
+			}
+			callee := typeutil.StaticCallee(info, n)
+			if callee == nil {
+				break
+			}
+			calleeFact := facts.funcFact(callee)
+			if calleeFact == nil {
+				break
+			}
+			if calleeFact.SpawnsGoroutine {
+				fact.SpawnsGoroutine = true
+			}
+			calleeSig := callee.Type().(*types.Signature)
+			if sel, ok := ast.Unparen(n.Fun).(*ast.SelectorExpr); ok && calleeFact.ReceiverEscapes && calleeSig.Recv() != nil {
+				escape(sel.X)
+			}
+			for i, arg := range n.Args {
+				if calleeFact.paramEscapes(i, calleeSig) {
+					escape(arg)
+				}
+				if calleeFact.paramClosed(i, calleeSig) {
+					closeParam(arg)
+				}
+			}
+		}
+		return true
+	})
+
+	for i := 0; i < sig.Params().Len(); i++ {
+		if closes[i] {
+			fact.ClosedParams = append(fact.ClosedParams, i)
+		}
+	}
+	for i := -1; i < sig.Params().Len(); i++ {
+		if !escapes[i] {
+			continue
+		}
+		if i < 0 {
+			fact.ReceiverEscapes = true
+		} else {
+			fact.EscapingParams = append(fact.EscapingParams, i)
+		}
+	}
+	return fact
+}
+
+// lockedFields returns the paths from recv to the mutexes body locks on its
+// own goroutine, by calling Lock or RLock on them or a method whose fact
+// says it locks them.
+func lockedFields(info *types.Info, recv *types.Var, body ast.Node, facts *factSet) []string {
+	var paths []string
+	seen := map[string]bool{}
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GoStmt, *ast.FuncLit, *ast.DeferStmt:
+			return false
+		case *ast.CallExpr:
+			sel, ok := ast.Unparen(n.Fun).(*ast.SelectorExpr)
+			if !ok {
+				break
+			}
+			path, ok := fieldPath(info, sel.X, recv)
+			if !ok {
+				break
+			}
+			if (sel.Sel.Name == "Lock" || sel.Sel.Name == "RLock") && len(n.Args) == 0 {
+				add(path)
+			} else if callee := typeutil.StaticCallee(info, n); callee != nil {
+				if fact := facts.funcFact(callee); fact != nil {
+					for _, locked := range fact.LockedFields {
+						add(joinPath(path, locked))
+					}
+				}
+			}
+		}
+		return true
+	})
+	return paths
+}
+
+// fieldPath returns the path of fields from recv that expr selects, such as
+// "state.mu" for r.state.mu, and whether expr selects one at all.
+func fieldPath(info *types.Info, expr ast.Expr, recv *types.Var) (string, bool) {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		return "", info.Uses[e] == recv
+	case *ast.StarExpr:
+		return fieldPath(info, e.X, recv)
+	case *ast.SelectorExpr:
+		if sel := info.Selections[e]; sel == nil || sel.Kind() != types.FieldVal {
+			return "", false
+		}
+		path, ok := fieldPath(info, e.X, recv)
+		return joinPath(path, e.Sel.Name), ok
+	}
+	return "", false
+}
+
+// joinPath joins two field paths, either of which may be empty.
+func joinPath(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + "." + b
+}
+
+// exitFuncs are the functions ending the process without running deferred
+// calls, by the full name types.Func.FullName gives them.
+var exitFuncs = map[string]bool{
+	"os.Exit":               true,
+	"log.Fatal":             true,
+	"log.Fatalf":            true,
+	"log.Fatalln":           true,
+	"(*log.Logger).Fatal":   true,
+	"(*log.Logger).Fatalf":  true,
+	"(*log.Logger).Fatalln": true,
+}
+
+// exitsProcess returns the first call in body, outside go statements, of a
+// function in exitFuncs or of one whose fact says it may exit the process,
+// or nil.
+func exitsProcess(info *types.Info, body ast.Node, facts *factSet) *ast.CallExpr {
+	var exit *ast.CallExpr
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.GoStmt:
+			return false
+		case *ast.CallExpr:
+			callee := typeutil.StaticCallee(info, n)
+			if callee == nil {
+				break
+			}
+			if exitFuncs[callee.FullName()] {
+				exit = n
+			} else if fact := facts.funcFact(callee); fact != nil && fact.ExitsProcess {
+				exit = n
+			}
+		}
+		return exit == nil
+	})
+	return exit
+}
+
+// rootObject returns the variable an expression such as p, &p.field or
+// p.items[i] is rooted at, or nil.
+func rootObject(info *types.Info, expr ast.Expr) types.Object {
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			return info.Uses[e]
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			if _, ok := info.Selections[e]; !ok {
+				return nil
+			}
+			expr = e.X
+		case *ast.UnaryExpr:
+			if e.Op != token.AND {
+				return nil
+			}
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+var globalVar = &Check{
+	Name: "global-var",
+	ID:   "TS0004",
+	Doc:  "report package-level variables, which every goroutine shares",
+	Rationale: `A package-level variable is reachable from every goroutine in the program,
+so any write to it after initialization races with reads elsewhere unless
+every access is synchronized.`,
+	Bad: `var hits int
+
+func handle() {
+	hits++
+}`,
+	Good: `type server struct {
+	hits atomic.Int64
+}
+
+func (s *server) handle() {
+	s.hits.Add(1)
+}`,
+	Run: func(pass *Pass) {
+		for _, f := range pass.Files {
+			for _, decl := range f.Decls {
+				if decl, ok := decl.(*ast.GenDecl); ok && decl.Tok == token.VAR {
+					for _, spec := range decl.Specs {
+						pass.Reportf(spec, nil, "global var declared")
+					}
+				}
+			}
+		}
+	},
+}
@@ -0,0 +1,211 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+var goPointerCall = &Check{
+	Name: "go-pointer-call",
+	ID:   "TS0002",
+	Doc:  "report go statements calling methods on receivers containing pointers, func values, or closures capturing pointers or variables written on either side",
+	Rationale: `A closure run as a goroutine shares every variable it captures with the
+function that created it, and a method value shares its receiver. Both sides
+can then read and write the same memory concurrently: through captured
+variables holding pointers, or through the captured variable itself when one
+side writes it while the other uses it after the go statement.`,
+	Bad: `count := 0
+go func() {
+	count++ // races with the read below
+}()
+fmt.Println(count)`,
+	Good: `done := make(chan int)
+go func(count int) {
+	done <- count + 1
+}(0)
+fmt.Println(<-done)`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil)}, func(n ast.Node) {
+			stmt := n.(*ast.GoStmt)
+			if lit, ok := ast.Unparen(stmt.Call.Fun).(*ast.FuncLit); ok {
+				checkCaptures(pass, stmt, lit, "goroutine", "its spawner")
+				return
+			}
+			switch fun := ast.Unparen(stmt.Call.Fun).(type) {
+			case *ast.SelectorExpr:
+				if selection, ok := pass.TypesInfo.Selections[fun]; ok && selection.Kind() == types.MethodVal {
+					checkReceiver(pass, fun)
+					return
+				}
+				if _, ok := pass.TypesInfo.Uses[fun.Sel].(*types.Func); ok {
+					// A function of another package shares nothing itself.
+					return
+				}
+			case *ast.Ident:
+				switch pass.TypesInfo.Uses[fun].(type) {
+				case *types.Func, *types.Builtin:
+					return
+				}
+			}
+			if contains, pointerType := pass.ContainsPointer(pass.TypesInfo.TypeOf(stmt.Call.Fun)); contains {
+				pass.Reportf(stmt, pointerType, "calling goroutine on a pointer type")
+			}
+		})
+	},
+}
+
+var goPointerArg = &Check{
+	Name: "go-pointer-arg",
+	ID:   "TS0003",
+	Doc:  "report arguments containing pointers passed to go statements",
+	Rationale: `Arguments to a go statement are evaluated by the caller and then used by
+the new goroutine. When an argument contains a pointer, both goroutines hold
+the memory it refers to.`,
+	Bad: `buf := make([]byte, 1024)
+go fill(buf)
+use(buf) // races with fill`,
+	Good: `go fill(make([]byte, 1024)) // nothing else refers to the buffer`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil)}, func(n ast.Node) {
+			call := n.(*ast.GoStmt).Call
+			for i, arg := range call.Args {
+				if pass.ownedArg(call, i) || pass.isFresh(arg) {
+					continue
+				}
+				payload := pass.TypesInfo.TypeOf(arg)
+				if contains, pointerType := pass.ContainsPointer(payload); contains {
+					finding := pass.NewFinding(arg, pointerType, "calling goroutine with a pointer type%s", cloneHint(payload))
+					finding.Nested = isNested(payload, pointerType)
+					pass.ReportFinding(finding)
+				}
+			}
+		})
+	},
+}
+
+var readOnlySharing = &Check{
+	Name:         "read-only-sharing",
+	ID:           "TS0053",
+	Doc:          "report variables captured by goroutines and used by their spawners too, even though neither side writes them",
+	OffByDefault: true,
+	Rationale: `A variable that a goroutine and the function starting it both read, and
+neither writes, is shared without racing, so go-pointer-call leaves it alone.
+It is still shared memory: the first write added to either side, however far
+from the go statement, races with the other. Passing the value as an
+argument gives the goroutine its own copy and keeps the two independent.`,
+	Bad: `limit := cfg.Limit
+go func() {
+	trim(limit)
+}()
+log.Println(limit)`,
+	Good: `limit := cfg.Limit
+go func(limit int) {
+	trim(limit)
+}(limit)
+log.Println(limit)`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil)}, func(n ast.Node) {
+			stmt := n.(*ast.GoStmt)
+			lit, ok := ast.Unparen(stmt.Call.Fun).(*ast.FuncLit)
+			if !ok {
+				return
+			}
+			for _, id := range capturedVars(pass.TypesInfo, lit) {
+				v := pass.TypesInfo.Uses[id].(*types.Var)
+				if pass.isFresh(id) {
+					continue
+				}
+				if contains, _ := pass.ContainsPointer(v.Type()); contains {
+					// go-pointer-call reports it.
+					continue
+				}
+				if shared, written := sharedCapture(pass, stmt, lit, v); shared && !written {
+					pass.Reportf(id, nil, "goroutine and its spawner both read captured variable; pass it as an argument instead")
+				}
+			}
+		})
+	},
+}
+
+// checkReceiver reports the receiver of the method a go statement calls if
+// it is shared with the goroutine: when it contains pointers, or when the
+// method has a pointer receiver and calling it takes the receiver's address.
+func checkReceiver(pass *Pass, sel *ast.SelectorExpr) {
+	recv := pass.TypesInfo.TypeOf(sel.X)
+	if addressedReceiver(pass.TypesInfo, sel) && !pass.isSafeType(types.NewPointer(recv)) {
+		pass.Reportf(sel.X, types.NewPointer(recv), "calling goroutine on a method with a pointer receiver")
+		return
+	}
+	if contains, pointerType := pass.ContainsPointer(recv); contains {
+		pass.Reportf(sel.X, pointerType, "calling goroutine on a method of a pointer type")
+	}
+}
+
+// checkCaptures reports the variables lit captures that stmt shares with the
+// spawning function: those holding pointers, and those one side writes while
+// the other uses them after stmt, or in any iteration of a loop around it.
+// The messages call the side running lit subject and the other spawner.
+func checkCaptures(pass *Pass, stmt ast.Node, lit *ast.FuncLit, subject, spawner string) {
+	for _, id := range capturedVars(pass.TypesInfo, lit) {
+		v := pass.TypesInfo.Uses[id].(*types.Var)
+		if pass.isFresh(id) {
+			continue
+		}
+		if contains, pointerType := pass.ContainsPointer(v.Type()); contains {
+			pass.Reportf(id, pointerType, "%s captures pointer type", subject)
+			continue
+		}
+		if shared, written := sharedCapture(pass, stmt, lit, v); shared && written {
+			pass.Reportf(id, nil, "%s and %s both use captured variable, and one of them writes it", subject, spawner)
+		}
+	}
+}
+
+// sharedCapture reports whether lit and the function around stmt both use
+// the captured variable v, the latter after stmt or in any iteration of a
+// loop around it, and whether either side writes it.
+func sharedCapture(pass *Pass, stmt ast.Node, lit *ast.FuncLit, v *types.Var) (shared, written bool) {
+	body, loop := enclosingFunc(pass, stmt, v.Pos())
+	if body == nil {
+		return false, false
+	}
+	writes := writtenIdents(body)
+	var inside, outside bool
+	ast.Inspect(body, func(n ast.Node) bool {
+		use, ok := n.(*ast.Ident)
+		if !ok || pass.TypesInfo.Uses[use] != v {
+			return true
+		}
+		switch {
+		case use.Pos() >= lit.Pos() && use.Pos() < lit.End():
+			inside = true
+			written = written || writes[use]
+		case use.Pos() >= stmt.End() || loop != nil && use.Pos() >= loop.Pos() && use.Pos() < loop.End():
+			outside = true
+			written = written || writes[use]
+		}
+		return true
+	})
+	return inside && outside, written
+}
+
+// writtenIdents returns the identifiers in body assigned or incremented.
+func writtenIdents(body *ast.BlockStmt) map[*ast.Ident]bool {
+	writes := map[*ast.Ident]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				if id, ok := ast.Unparen(lhs).(*ast.Ident); ok {
+					writes[id] = true
+				}
+			}
+		case *ast.IncDecStmt:
+			if id, ok := ast.Unparen(n.X).(*ast.Ident); ok {
+				writes[id] = true
+			}
+		}
+		return true
+	})
+	return writes
+}
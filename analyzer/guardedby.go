@@ -0,0 +1,266 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+var guardedBy = &Check{
+	Name: "guarded-by",
+	ID:   "TS0009",
+	Doc:  "report accesses to fields and variables annotated //tsgo:guardedby made without holding the named mutex",
+	Rationale: `A //tsgo:guardedby annotation documents that a field or variable may only be
+used while a mutex is held. An access made without locking it, or a write
+made under a read lock only, races with the goroutines that respect the
+annotation. The check is lexical: the mutex must be locked earlier in the
+same function, and not unlocked again before the access. Functions whose
+names end in "Locked" are assumed to be called with the mutex held.`,
+	Bad: `type Counter struct {
+	mu sync.Mutex
+	n  int //tsgo:guardedby mu
+}
+
+func (c *Counter) Get() int {
+	return c.n
+}`,
+	Good: `func (c *Counter) Get() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.n
+}`,
+	Run: func(pass *Pass) {
+		guards := collectGuards(pass, func(c *ast.Comment, message string) {
+			pass.Reportf(c, nil, "%s", message)
+		})
+		if len(guards) == 0 {
+			return
+		}
+		for _, f := range pass.Files {
+			for _, decl := range f.Decls {
+				if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil && !strings.HasSuffix(fd.Name.Name, "Locked") {
+					checkGuardedAccesses(pass, guards, fd.Body)
+				}
+			}
+		}
+	},
+}
+
+// A guard is the mutex named by a //tsgo:guardedby annotation.
+type guard struct {
+	// name is the mutex as written in the annotation.
+	name string
+	// embedded is set when the mutex is an embedded field, which may also
+	// be locked through the struct itself.
+	embedded bool
+}
+
+// collectGuards returns the guards of the annotated fields and package-level
+// variables of the package, passing annotations naming no mutex to invalid.
+func collectGuards(pass *Pass, invalid func(c *ast.Comment, message string)) map[types.Object]guard {
+	guards := map[types.Object]guard{}
+	annotated := func(fieldDoc, fieldComment *ast.CommentGroup) (string, *ast.Comment) {
+		if name, c := directiveArgs(fieldDoc, guardedByDirective); c != nil {
+			return name, c
+		}
+		return directiveArgs(fieldComment, guardedByDirective)
+	}
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			st, ok := n.(*ast.StructType)
+			if !ok {
+				return true
+			}
+			for _, field := range st.Fields.List {
+				name, c := annotated(field.Doc, field.Comment)
+				if c == nil {
+					continue
+				}
+				g, ok := siblingGuard(st, name)
+				if !ok {
+					invalid(c, "guardedby names no field of the struct")
+					continue
+				}
+				for _, id := range field.Names {
+					if obj := pass.TypesInfo.Defs[id]; obj != nil {
+						guards[obj] = g
+					}
+				}
+			}
+			return true
+		})
+		for _, decl := range f.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+				doc := vs.Doc
+				if doc == nil && len(gd.Specs) == 1 {
+					doc = gd.Doc
+				}
+				name, c := annotated(doc, vs.Comment)
+				if c == nil {
+					continue
+				}
+				root, _, _ := strings.Cut(name, ".")
+				if _, ok := pass.Pkg.Scope().Lookup(root).(*types.Var); !ok || name == "" {
+					invalid(c, "guardedby names no package-level variable")
+					continue
+				}
+				for _, id := range vs.Names {
+					if obj := pass.TypesInfo.Defs[id]; obj != nil {
+						guards[obj] = guard{name: name}
+					}
+				}
+			}
+		}
+	}
+	return guards
+}
+
+// siblingGuard returns the guard for the field of st called name.
+func siblingGuard(st *ast.StructType, name string) (guard, bool) {
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			t := field.Type
+			if star, ok := t.(*ast.StarExpr); ok {
+				t = star.X
+			}
+			if sel, ok := t.(*ast.SelectorExpr); ok {
+				t = sel.Sel
+			}
+			if id, ok := t.(*ast.Ident); ok && id.Name == name {
+				return guard{name: name, embedded: true}, true
+			}
+		}
+		for _, id := range field.Names {
+			if id.Name == name {
+				return guard{name: name}, true
+			}
+		}
+	}
+	return guard{}, false
+}
+
+// A lockEvent is a call locking or unlocking a mutex in a function body.
+type lockEvent struct {
+	pos token.Pos
+	// mutex is the source of the locked expression, e.g. "c.mu".
+	mutex string
+	// held is the lock held after the call: "Lock", "RLock" or "" when
+	// unlocked.
+	held string
+}
+
+// checkGuardedAccesses reports the accesses to guarded objects in body made
+// without the guard held. Function literals are checked separately, since
+// they run at some other time.
+func checkGuardedAccesses(pass *Pass, guards map[types.Object]guard, body *ast.BlockStmt) {
+	deferred := map[*ast.CallExpr]bool{}
+	writes := map[ast.Expr]bool{}
+	var events []lockEvent
+	var lits []*ast.FuncLit
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			lits = append(lits, n)
+			return false
+		case *ast.DeferStmt:
+			deferred[n.Call] = true
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				writes[writtenExpr(lhs)] = true
+			}
+		case *ast.IncDecStmt:
+			writes[writtenExpr(n.X)] = true
+		case *ast.CallExpr:
+			sel, ok := ast.Unparen(n.Fun).(*ast.SelectorExpr)
+			if !ok || deferred[n] {
+				break
+			}
+			held := ""
+			switch sel.Sel.Name {
+			case "Lock", "RLock":
+				held = sel.Sel.Name
+			case "Unlock", "RUnlock":
+			default:
+				return true
+			}
+			events = append(events, lockEvent{pos: n.Pos(), mutex: stringifyNode(pass.Fset, sel.X), held: held})
+		}
+		return true
+	})
+
+	heldAt := func(pos token.Pos, mutexes ...string) string {
+		held := ""
+		for _, event := range events {
+			if event.pos >= pos {
+				break
+			}
+			for _, mutex := range mutexes {
+				if event.mutex == mutex {
+					held = event.held
+				}
+			}
+		}
+		return held
+	}
+	check := func(node ast.Expr, mutexes ...string) {
+		held := heldAt(node.Pos(), mutexes...)
+		switch {
+		case held == "" && writes[node]:
+			pass.Reportf(node, nil, "writing variable guarded by %s without holding it", mutexes[0])
+		case held == "":
+			pass.Reportf(node, nil, "reading variable guarded by %s without holding it", mutexes[0])
+		case held == "RLock" && writes[node]:
+			pass.Reportf(node, nil, "writing variable guarded by %s while holding only a read lock", mutexes[0])
+		}
+	}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.SelectorExpr:
+			selection, ok := pass.TypesInfo.Selections[n]
+			if !ok || selection.Kind() != types.FieldVal {
+				break
+			}
+			if g, ok := guards[selection.Obj()]; ok {
+				x := stringifyNode(pass.Fset, n.X)
+				if g.embedded {
+					check(n, x+"."+g.name, x)
+				} else {
+					check(n, x+"."+g.name)
+				}
+			}
+		case *ast.Ident:
+			if v, ok := pass.TypesInfo.Uses[n].(*types.Var); ok && !v.IsField() {
+				if g, ok := guards[v]; ok {
+					check(n, g.name)
+				}
+			}
+		}
+		return true
+	})
+	for _, lit := range lits {
+		checkGuardedAccesses(pass, guards, lit.Body)
+	}
+}
+
+// writtenExpr returns the variable or field that assigning to lhs writes,
+// looking through indexing so that writing m[k] counts as writing m.
+func writtenExpr(lhs ast.Expr) ast.Expr {
+	for {
+		switch e := lhs.(type) {
+		case *ast.ParenExpr:
+			lhs = e.X
+		case *ast.IndexExpr:
+			lhs = e.X
+		default:
+			return lhs
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+var interfacePayload = &Check{
+	Name:         "interface-payload",
+	ID:           "TS0008",
+	Doc:          "report interface values, other than errors, sent over channels or passed to go statements",
+	OffByDefault: true,
+	Rationale: `An interface value hides its dynamic type, which may well be a pointer or
+contain one. The pointer checks cannot see through it, so sending an
+interface over a channel or handing it to a goroutine may share memory
+without being reported. Errors are exempt, being conventionally immutable.
+A local variable is only reported when one of the concrete values assigned to
+it contains a pointer, unless a value of unknown dynamic type reaches it.`,
+	Bad: `var events chan any
+events <- state // state may be a *State`,
+	Good: `var events chan Event
+events <- Event{Name: state.Name}`,
+	Run: func(pass *Pass) {
+		dynamic := collectDynamicTypes(pass)
+		// check reports expr unless it is a variable known to hold only
+		// values without pointers.
+		check := func(expr ast.Expr, message, holdingMessage string) {
+			t := interfaceType(pass, expr)
+			if t == nil {
+				return
+			}
+			if id, ok := ast.Unparen(expr).(*ast.Ident); ok {
+				if held, known := dynamic.of(pass.TypesInfo.Uses[id]); known {
+					for _, h := range held {
+						if contains, pointerType := pass.ContainsPointer(h); contains {
+							pass.Reportf(expr, pointerType, "%s", holdingMessage)
+							return
+						}
+					}
+					return
+				}
+			}
+			pass.Reportf(expr, t, "%s", message)
+		}
+		pass.Inspector.Preorder([]ast.Node{(*ast.SendStmt)(nil), (*ast.GoStmt)(nil)}, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.SendStmt:
+				check(n.Value, "sending interface value over a channel", "sending interface value holding a pointer over a channel")
+			case *ast.GoStmt:
+				for _, arg := range n.Call.Args {
+					check(arg, "calling goroutine with an interface value", "calling goroutine with an interface value holding a pointer")
+				}
+			}
+		})
+	},
+}
+
+// dynamicTypes records the concrete types assigned to the local interface
+// variables of a package declared by an assignment or var declaration.
+type dynamicTypes struct {
+	declared map[*types.Var]bool
+	held     map[*types.Var][]types.Type
+	// unknown holds the variables assigned an interface value, or whose
+	// address is taken.
+	unknown map[*types.Var]bool
+}
+
+// collectDynamicTypes returns the dynamic types of the local interface
+// variables of the package.
+func collectDynamicTypes(pass *Pass) *dynamicTypes {
+	d := &dynamicTypes{declared: map[*types.Var]bool{}, held: map[*types.Var][]types.Type{}, unknown: map[*types.Var]bool{}}
+	local := func(expr ast.Expr) *types.Var {
+		id, ok := ast.Unparen(expr).(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		obj := pass.TypesInfo.Defs[id]
+		if obj == nil {
+			obj = pass.TypesInfo.Uses[id]
+		}
+		v, ok := obj.(*types.Var)
+		if ok && pass.TypesInfo.Defs[id] != nil {
+			d.declared[v] = true
+		}
+		if !ok || v.IsField() || v.Parent() == nil || v.Parent() == v.Pkg().Scope() || !types.IsInterface(v.Type()) {
+			return nil
+		}
+		return v
+	}
+	add := func(v *types.Var, tv types.TypeAndValue) {
+		switch {
+		case tv.IsNil():
+		case tv.Type == nil || types.IsInterface(tv.Type):
+			d.unknown[v] = true
+		default:
+			d.held[v] = append(d.held[v], tv.Type)
+		}
+	}
+	assign := func(lhs, rhs []ast.Expr) {
+		for i, expr := range lhs {
+			v := local(expr)
+			if v == nil {
+				continue
+			}
+			switch {
+			case len(rhs) == 0:
+				// The zero value holds nothing.
+			case len(rhs) == len(lhs):
+				add(v, pass.TypesInfo.Types[rhs[i]])
+			default:
+				if tuple, ok := pass.TypesInfo.TypeOf(rhs[0]).(*types.Tuple); ok && i < tuple.Len() {
+					add(v, types.TypeAndValue{Type: tuple.At(i).Type()})
+				} else {
+					d.unknown[v] = true
+				}
+			}
+		}
+	}
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				assign(n.Lhs, n.Rhs)
+			case *ast.ValueSpec:
+				lhs := make([]ast.Expr, len(n.Names))
+				for i, id := range n.Names {
+					lhs[i] = id
+				}
+				assign(lhs, n.Values)
+			case *ast.UnaryExpr:
+				if v := local(n.X); v != nil && n.Op == token.AND {
+					d.unknown[v] = true
+				}
+			}
+			return true
+		})
+	}
+	return d
+}
+
+// of returns the concrete types assigned to obj, and whether they are all
+// known.
+func (d *dynamicTypes) of(obj types.Object) ([]types.Type, bool) {
+	v, ok := obj.(*types.Var)
+	if !ok || !d.declared[v] || d.unknown[v] {
+		return nil, false
+	}
+	return d.held[v], true
+}
+
+// interfaceType returns the type of expr if it is an interface type that
+// interface-payload reports, or nil.
+func interfaceType(pass *Pass, expr ast.Expr) types.Type {
+	t := pass.TypesInfo.TypeOf(expr)
+	if t == nil || !types.IsInterface(t) || isError(t) || pass.isSafeType(t) {
+		return nil
+	}
+	if _, ok := t.(*types.TypeParam); ok {
+		return nil
+	}
+	return t
+}
+
+// isError reports whether t is the predeclared error type.
+func isError(t types.Type) bool {
+	return types.Identical(t, types.Universe.Lookup("error").Type())
+}
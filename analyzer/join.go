@@ -0,0 +1,177 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+var unjoinedGoroutine = &Check{
+	Name:     "unjoined-goroutine",
+	ID:       "TS0022",
+	Group:    resourceLeakGroup,
+	Doc:      "note go statements whose goroutine nothing waits for",
+	Severity: SeverityInfo,
+	Rationale: `A goroutine nobody waits for outlives the function that started it. Its
+errors go unreported, it may still be using what its spawner frees or
+returns, and tests and shutdown cannot tell when it is done. The check is a
+heuristic: a goroutine counts as joined when its spawner calls a Wait
+method, as on a sync.WaitGroup or errgroup.Group, or when it uses a channel
+or WaitGroup that its spawner also receives from, returns or hands on, or
+that came from elsewhere. A WaitGroup field counts too: a goroutine started
+after calling Add on a field is joined if code in the package, such as a
+Close method, waits on the same field.`,
+	Bad: `func save(r *Record) {
+	go store(r) // errors are lost, and save returns before r is stored
+}`,
+	Good: `func save(r *Record) error {
+	done := make(chan error)
+	go func() { done <- store(r) }()
+	return <-done
+}`,
+	Run: func(pass *Pass) {
+		waited := waitedFields(pass)
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil)}, func(n ast.Node) {
+			stmt := n.(*ast.GoStmt)
+			body, _ := enclosingFunc(pass, stmt, stmt.Pos())
+			if body == nil || joined(pass, body, stmt) || addsToWaitedField(pass, body, stmt, waited) {
+				return
+			}
+			reportGoStmt(pass, stmt, "goroutine is never joined: nothing waits for it to finish")
+		})
+	},
+}
+
+// reportGoStmt reports message about stmt, abbreviating the body of a
+// function literal it starts.
+func reportGoStmt(pass *Pass, stmt *ast.GoStmt, message string) {
+	finding := pass.NewFinding(stmt, nil, "%s", message)
+	if _, ok := ast.Unparen(stmt.Call.Fun).(*ast.FuncLit); ok {
+		finding.Node = "go func() {...}()"
+	}
+	pass.ReportFinding(finding)
+}
+
+// joined reports whether the goroutine stmt starts appears to be waited for:
+// whether body calls a Wait method outside stmt, or stmt uses a channel or
+// WaitGroup that was not made by body or that body uses outside stmt other
+// than to send on or close it.
+func joined(pass *Pass, body *ast.BlockStmt, stmt *ast.GoStmt) bool {
+	inside := func(n ast.Node) bool {
+		return n.Pos() >= stmt.Pos() && n.End() <= stmt.End()
+	}
+	waits := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && !inside(call) {
+			if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok && sel.Sel.Name == "Wait" {
+				waits = true
+			}
+		}
+		return !waits
+	})
+	if waits {
+		return true
+	}
+	used := map[*types.Var]bool{}
+	ast.Inspect(stmt.Call, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			if v, ok := pass.TypesInfo.Uses[id].(*types.Var); ok && isJoinHandle(v.Type()) {
+				used[v] = true
+			}
+		}
+		return true
+	})
+	for v := range used {
+		if v.Pos() < body.Pos() || v.Pos() >= body.End() {
+			// A channel or WaitGroup from elsewhere may be waited on there.
+			return true
+		}
+	}
+	// sendsOrCloses holds the uses of the variables on the sending side.
+	sendsOrCloses := map[*ast.Ident]bool{}
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SendStmt:
+			if id, ok := ast.Unparen(n.Chan).(*ast.Ident); ok {
+				sendsOrCloses[id] = true
+			}
+		case *ast.CallExpr:
+			if fun, ok := ast.Unparen(n.Fun).(*ast.Ident); ok && fun.Name == "close" && len(n.Args) == 1 {
+				if id, ok := ast.Unparen(n.Args[0]).(*ast.Ident); ok {
+					sendsOrCloses[id] = true
+				}
+			}
+		}
+		return true
+	})
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || inside(id) || sendsOrCloses[id] {
+			return !found
+		}
+		if v, ok := pass.TypesInfo.Uses[id].(*types.Var); ok && used[v] {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// waitedFields returns the WaitGroup fields the package calls Wait on, such
+// as wg in p.wg.Wait().
+func waitedFields(pass *Pass) map[types.Object]bool {
+	waited := map[types.Object]bool{}
+	pass.Inspector.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		if field := waitGroupFieldCall(pass.TypesInfo, n.(*ast.CallExpr), "Wait"); field != nil {
+			waited[field] = true
+		}
+	})
+	return waited
+}
+
+// addsToWaitedField reports whether body calls Add on one of the waited
+// WaitGroup fields before stmt, as a constructor does before starting a
+// worker that a Close method waits for.
+func addsToWaitedField(pass *Pass, body *ast.BlockStmt, stmt *ast.GoStmt, waited map[types.Object]bool) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil || n.Pos() >= stmt.Pos() {
+			return false
+		}
+		if call, ok := n.(*ast.CallExpr); ok && call.End() <= stmt.Pos() {
+			found = waited[waitGroupFieldCall(pass.TypesInfo, call, "Add")]
+		}
+		return !found
+	})
+	return found
+}
+
+// waitGroupFieldCall returns the WaitGroup field whose method named method
+// call calls, or nil if call is no such call.
+func waitGroupFieldCall(info *types.Info, call *ast.CallExpr, method string) types.Object {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != method {
+		return nil
+	}
+	field := accessedVar(info, ast.Unparen(sel.X))
+	if field == nil || !field.(*types.Var).IsField() || !isJoinHandle(field.Type()) {
+		return nil
+	}
+	if _, ok := field.Type().Underlying().(*types.Chan); ok {
+		return nil
+	}
+	return field
+}
+
+// isJoinHandle reports whether values of type t can be used to wait for a
+// goroutine: channels, and sync.WaitGroup or pointers to it.
+func isJoinHandle(t types.Type) bool {
+	if _, ok := t.Underlying().(*types.Chan); ok {
+		return true
+	}
+	if ptr, ok := types.Unalias(t).(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "sync" && named.Obj().Name() == "WaitGroup"
+}
@@ -0,0 +1,265 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// resourceLeakGroup is the group of checks reporting timers, tickers and
+// goroutines left running.
+const resourceLeakGroup = "resource-leak"
+
+var timeAfterLoop = &Check{
+	Name:  "time-after-loop",
+	ID:    "TS0031",
+	Group: resourceLeakGroup,
+	Doc:   "report time.After called in loops",
+	Rationale: `Each call of time.After creates a new timer. In a loop, typically as a
+select case, that allocates a timer per iteration, which before Go 1.23 is
+not freed until it fires, and restarts the timeout every time another case
+is chosen, so that it may never fire at all. Create one time.Timer or
+time.Ticker outside the loop and reset it instead.`,
+	Bad: `for {
+	select {
+	case msg := <-msgs:
+		handle(msg)
+	case <-time.After(time.Minute):
+		return
+	}
+}`,
+	Good: `timer := time.NewTimer(time.Minute)
+defer timer.Stop()
+for {
+	select {
+	case msg := <-msgs:
+		handle(msg)
+		timer.Reset(time.Minute)
+	case <-timer.C:
+		return
+	}
+}`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+			call := n.(*ast.CallExpr)
+			callee := typeutil.StaticCallee(pass.TypesInfo, call)
+			if callee == nil || callee.FullName() != "time.After" || !inLoop(enclosingPath(pass, call)) {
+				return
+			}
+			pass.Reportf(call, nil, "time.After in a loop creates a timer per iteration; reuse a time.Timer instead")
+		})
+	},
+}
+
+// inLoop reports whether the node at the head of path is in the body of a
+// loop of the function containing it.
+func inLoop(path []ast.Node) bool {
+	for i, n := range path {
+		switch n := n.(type) {
+		case *ast.ForStmt:
+			if i > 0 && path[i-1] == n.Body {
+				return true
+			}
+		case *ast.RangeStmt:
+			if i > 0 && path[i-1] == n.Body {
+				return true
+			}
+		case *ast.FuncLit, *ast.FuncDecl:
+			return false
+		}
+	}
+	return false
+}
+
+var unstoppedTicker = &Check{
+	Name:  "unstopped-ticker",
+	ID:    "TS0032",
+	Group: resourceLeakGroup,
+	Doc:   "report tickers from time.NewTicker not stopped on every return, and time.Tick outside main",
+	Rationale: `A ticker keeps ticking until it is stopped, waking the runtime and, before
+Go 1.23, never being freed. A function creating one with time.NewTicker
+should defer its Stop, or stop it before every return. time.Tick returns a
+ticker channel that cannot be stopped at all; it is only fit for tickers
+meant to run as long as the program does, in main or init.`,
+	Bad: `func poll(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			refresh()
+		case <-done:
+			return // the ticker keeps running
+		}
+	}
+}`,
+	Good: `func poll(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			refresh()
+		case <-done:
+			return
+		}
+	}
+}`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+			call := n.(*ast.CallExpr)
+			callee := typeutil.StaticCallee(pass.TypesInfo, call)
+			if callee == nil {
+				return
+			}
+			path := enclosingPath(pass, call)
+			switch callee.FullName() {
+			case "time.Tick":
+				if fd, ok := funcOf(path).(*ast.FuncDecl); !ok || fd.Recv != nil || fd.Name.Name != "init" && (fd.Name.Name != "main" || pass.Pkg.Name() != "main") {
+					pass.Reportf(call, nil, "time.Tick creates a ticker that can never be stopped; use time.NewTicker and stop it")
+				}
+			case "time.NewTicker":
+				if len(path) < 2 {
+					return
+				}
+				assign, ok := path[1].(*ast.AssignStmt)
+				if !ok || len(assign.Lhs) != 1 {
+					return
+				}
+				id, ok := assign.Lhs[0].(*ast.Ident)
+				if !ok {
+					return
+				}
+				v, ok := pass.TypesInfo.ObjectOf(id).(*types.Var)
+				if !ok || funcOf(path) == nil {
+					return
+				}
+				if stop := tickerStop(pass, funcOf(path), v, assign, path); stop != "" {
+					pass.Reportf(call, nil, "%s", stop)
+				}
+			}
+		})
+	},
+}
+
+// tickerStop returns why the ticker held by v, created by assign at the head
+// of path in fn, may be left running, or "" if it is stopped: it is never
+// stopped, or some return after creating it is not preceded by a Stop in a
+// block enclosing it. Tickers v hands on to other code are taken as stopped
+// there.
+func tickerStop(pass *Pass, fn ast.Node, v *types.Var, assign *ast.AssignStmt, path []ast.Node) string {
+	var stops []*ast.CallExpr
+	deferredStop, escapes := false, false
+	ast.Inspect(fn, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.DeferStmt:
+			if isStopCall(pass.TypesInfo, n.Call, v) {
+				deferredStop = true
+			}
+		case *ast.CallExpr:
+			if isStopCall(pass.TypesInfo, n, v) {
+				stops = append(stops, n)
+			}
+		case *ast.SelectorExpr:
+			if id, ok := n.X.(*ast.Ident); ok && pass.TypesInfo.Uses[id] == v {
+				// Selecting C, Reset or Stop keeps the ticker here.
+				return false
+			}
+		case *ast.Ident:
+			if pass.TypesInfo.Uses[n] == v {
+				escapes = true
+			}
+		}
+		return true
+	})
+	if deferredStop || escapes {
+		return ""
+	}
+	if len(stops) == 0 {
+		return "ticker is never stopped"
+	}
+	stopped := func(pos token.Pos) bool {
+		for _, stop := range stops {
+			if stop.Pos() < assign.End() || stop.Pos() >= pos {
+				continue
+			}
+			for _, n := range enclosingPath(pass, stop)[1:] {
+				switch n.(type) {
+				case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+					if n.Pos() <= pos && pos < n.End() {
+						return true
+					}
+				default:
+					continue
+				}
+				break
+			}
+		}
+		return false
+	}
+	var unstopped bool
+	ast.Inspect(fn, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return n == fn
+		case *ast.ReturnStmt:
+			if n.Pos() > assign.End() && !stopped(n.Pos()) {
+				unstopped = true
+			}
+		}
+		return !unstopped
+	})
+	for _, n := range path[1:] {
+		if block, ok := n.(*ast.BlockStmt); ok {
+			// Falling off the end of the block creating the ticker.
+			if last := block.List[len(block.List)-1]; !terminates(last) {
+				unstopped = unstopped || !stopped(block.End()-1)
+			}
+			break
+		}
+	}
+	if unstopped {
+		return "ticker is not stopped on every return"
+	}
+	return ""
+}
+
+// terminates reports whether control cannot flow past stmt: it returns,
+// panics, or loops forever without breaking out.
+func terminates(stmt ast.Stmt) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStmt:
+		return true
+	case *ast.ExprStmt:
+		if call, ok := stmt.X.(*ast.CallExpr); ok {
+			if fun, ok := ast.Unparen(call.Fun).(*ast.Ident); ok && fun.Name == "panic" {
+				return true
+			}
+		}
+	case *ast.ForStmt:
+		if stmt.Cond != nil {
+			return false
+		}
+		breaks := false
+		ast.Inspect(stmt.Body, func(n ast.Node) bool {
+			if branch, ok := n.(*ast.BranchStmt); ok && (branch.Tok == token.BREAK || branch.Tok == token.GOTO) {
+				breaks = true
+			}
+			_, lit := n.(*ast.FuncLit)
+			return !breaks && !lit
+		})
+		return !breaks
+	}
+	return false
+}
+
+// isStopCall reports whether call is v.Stop().
+func isStopCall(info *types.Info, call *ast.CallExpr, v *types.Var) bool {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Stop" {
+		return false
+	}
+	id, ok := ast.Unparen(sel.X).(*ast.Ident)
+	return ok && info.Uses[id] == v
+}
@@ -0,0 +1,537 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+var missingUnlock = &Check{
+	Name: "missing-unlock",
+	ID:   "TS0045",
+	Doc:  "report mutexes locked and unlocked by a function that may return or panic with them still held",
+	Rationale: `A function that unlocks the mutex it locked on some paths, but returns or
+panics with it held on another, leaves it locked for good: the next
+goroutine to lock it blocks forever. The path is usually an early return
+added after the Unlock was written. Deferring the Unlock right after the
+Lock covers every path. Functions that never unlock the mutex they lock are
+assumed to hand it to their caller on purpose.`,
+	Bad: `c.mu.Lock()
+v, ok := c.m[key]
+if !ok {
+	return "", errNotFound // c.mu stays locked
+}
+c.mu.Unlock()
+return v, nil`,
+	Good: `c.mu.Lock()
+defer c.mu.Unlock()
+v, ok := c.m[key]
+if !ok {
+	return "", errNotFound
+}
+return v, nil`,
+	Run: func(pass *Pass) {
+		for _, body := range funcBodies(pass) {
+			flow := newLockFlow(pass, body)
+			// unlocked holds the mutexes the function unlocks, other than
+			// in deferred calls.
+			unlocked := map[string]bool{}
+			flow.each(func(op lockOp, _ lockState) {
+				if !op.locks() && !op.deferred {
+					unlocked[op.mutex] = true
+				}
+			})
+			reported := map[*ast.CallExpr]bool{}
+			flow.exits(func(exit ast.Node, state lockState) {
+				for mutex, held := range state.may {
+					if held.deferred || !unlocked[mutex] || reported[held.lock.call] {
+						continue
+					}
+					reported[held.lock.call] = true
+					where, related := describeExit(body, exit)
+					finding := pass.NewFinding(held.lock.call, nil, "%s is not unlocked on every path: it is still held %s", mutex, where)
+					if related {
+						finding.Relate(exit, "%s is still held here", mutex)
+					}
+					pass.ReportFinding(finding)
+				}
+			})
+		}
+	},
+}
+
+// describeExit describes where exit leaves body: "at a return", "at a panic"
+// or "at the end of the function", and reports whether exit is a statement
+// worth pointing at.
+func describeExit(body *ast.BlockStmt, exit ast.Node) (string, bool) {
+	if _, ok := exit.(*ast.ExprStmt); ok {
+		return "at a panic", true
+	}
+	if exit.Pos() == body.End()-1 {
+		return "at the end of the function", false
+	}
+	return "at a return", true
+}
+
+var undeferredUnlock = &Check{
+	Name:     "undeferred-unlock",
+	ID:       "TS0046",
+	Doc:      "note mutexes unlocked by hand after code that may panic",
+	Severity: SeverityInfo,
+	Rationale: `An Unlock written at the end of a critical section only runs if control
+gets there. A panic in the section, recovered further up, leaves the mutex
+locked, and every goroutine locking it next blocks forever. Deferring the
+Unlock right after the Lock makes it run whichever way the function leaves.
+Only sections that may panic, by calling panic or a must-style helper or
+asserting a type unchecked, are noted: ones unlocked by hand on every path
+out cannot otherwise go wrong, and ones missing an Unlock on some path are
+reported by missing-unlock. Critical sections of at most
+Config.MinCriticalSection statements are left alone.`,
+	Bad: `mu.Lock()
+re := regexp.MustCompile(patterns[key])
+cache[key] = re
+mu.Unlock()`,
+	Good: `mu.Lock()
+defer mu.Unlock()
+re := regexp.MustCompile(patterns[key])
+cache[key] = re`,
+	Run: func(pass *Pass) {
+		for _, body := range funcBodies(pass) {
+			flow := newLockFlow(pass, body)
+			// leaked holds the locks still held on leaving the function,
+			// which missing-unlock reports or the caller unlocks.
+			leaked := map[*ast.CallExpr]bool{}
+			flow.exits(func(_ ast.Node, state lockState) {
+				for _, held := range state.may {
+					leaked[held.lock.call] = true
+				}
+			})
+			var locks []lockOp
+			sections := map[*ast.CallExpr]*criticalSection{}
+			flow.nodes(func(n ast.Node, state lockState) {
+				for mutex, held := range state.may {
+					if held.deferred || leaked[held.lock.call] {
+						continue
+					}
+					section := sections[held.lock.call]
+					if section == nil {
+						section = &criticalSection{}
+						sections[held.lock.call] = section
+						locks = append(locks, held.lock)
+					}
+					if locksMutex(flow.ops[n], mutex) {
+						section.unlocked = true
+						continue
+					}
+					section.size++
+					if section.panics == nil {
+						section.panics, section.what = panicking(pass.TypesInfo, nodeBlock(n))
+					}
+				}
+			})
+			for _, lock := range locks {
+				section := sections[lock.call]
+				if !section.unlocked || section.panics == nil || section.size <= pass.minCriticalSection {
+					continue
+				}
+				finding := pass.NewFinding(lock.call, nil, "%s is unlocked by hand after code that may panic; defer %s.%s right after locking it", lock.mutex, lock.mutex, lock.unlockMethod())
+				finding.Relate(section.panics, "%s here may panic", section.what)
+				pass.ReportFinding(finding)
+			}
+		}
+	},
+}
+
+// A criticalSection is what a function does while holding a mutex it locked,
+// with no Unlock deferred.
+type criticalSection struct {
+	// size is the number of statements and conditions in the section.
+	size int
+	// panics is the first operation in the section that may panic, with
+	// what describing it, or nil if there is none.
+	panics ast.Node
+	what   string
+	// unlocked is set when the section ends in an Unlock.
+	unlocked bool
+}
+
+// locksMutex reports whether one of ops locks or unlocks mutex.
+func locksMutex(ops []lockOp, mutex string) bool {
+	for _, op := range ops {
+		if op.mutex == mutex {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeBlock returns a block holding what n, a node of a control-flow graph,
+// evaluates there: the statement or condition itself, or only the operand of
+// a range statement, whose body makes up nodes of its own.
+func nodeBlock(n ast.Node) *ast.BlockStmt {
+	switch n := n.(type) {
+	case *ast.RangeStmt:
+		return &ast.BlockStmt{List: []ast.Stmt{&ast.ExprStmt{X: n.X}}}
+	case ast.Stmt:
+		return &ast.BlockStmt{List: []ast.Stmt{n}}
+	case ast.Expr:
+		return &ast.BlockStmt{List: []ast.Stmt{&ast.ExprStmt{X: n}}}
+	}
+	return &ast.BlockStmt{}
+}
+
+var recursiveLock = &Check{
+	Name: "recursive-lock",
+	ID:   "TS0047",
+	Doc:  "report mutexes locked again, directly or by calling a method that locks them, while already held",
+	Rationale: `Go's mutexes are not reentrant: a goroutine locking a mutex it already holds
+waits for itself and deadlocks. The second Lock is often hidden in a method
+of the same type, which locks on the assumption that its callers do not.
+Read locks are no exception: a recursive RLock deadlocks as soon as a
+writer is waiting. Split the method into a locking wrapper and an unlocked
+body, conventionally named with a Locked suffix, and call the body.`,
+	Bad: `func (c *Cache) Refresh() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.m {
+		c.m[key] = c.Get(key) // Get locks c.mu too
+	}
+}`,
+	Good: `func (c *Cache) Refresh() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.m {
+		c.m[key] = c.getLocked(key)
+	}
+}`,
+	Run: func(pass *Pass) {
+		for _, body := range funcBodies(pass) {
+			flow := newLockFlow(pass, body)
+			flow.each(func(op lockOp, state lockState) {
+				if h, ok := state.must[op.mutex]; ok && op.locks() && !op.deferred {
+					finding := pass.NewFinding(op.call, nil, "%s is locked again while already held; Go mutexes are not reentrant, so this deadlocks", op.mutex)
+					finding.Relate(h.lock.call, "%s first locked here", op.mutex)
+					pass.ReportFinding(finding)
+				}
+			})
+			flow.nodes(func(n ast.Node, state lockState) {
+				if len(state.must) == 0 {
+					return
+				}
+				ast.Inspect(n, func(n ast.Node) bool {
+					switch n := n.(type) {
+					case *ast.FuncLit, *ast.GoStmt, *ast.DeferStmt:
+						return false
+					case *ast.CallExpr:
+						sel, ok := ast.Unparen(n.Fun).(*ast.SelectorExpr)
+						if !ok {
+							break
+						}
+						fn, ok := calledFunc(pass.TypesInfo, n)
+						if !ok {
+							break
+						}
+						fact := pass.facts.funcFact(fn)
+						if fact == nil {
+							break
+						}
+						x := stringifyNode(pass.Fset, sel.X)
+						for _, path := range fact.LockedFields {
+							mutex := joinPath(x, path)
+							if h, ok := state.must[mutex]; ok {
+								finding := pass.NewFinding(n, nil, "%s locks %s, which is already held; Go mutexes are not reentrant, so this deadlocks", fn.Name(), mutex)
+								finding.Relate(h.lock.call, "%s first locked here", mutex)
+								pass.ReportFinding(finding)
+								break
+							}
+						}
+					}
+					return true
+				})
+			})
+		}
+	},
+}
+
+var rwMutexMisuse = &Check{
+	Name: "rwmutex-misuse",
+	ID:   "TS0048",
+	Doc:  "report read locks released with Unlock, write locks released with RUnlock, RUnlock without an RLock and fields written under a read lock",
+	Rationale: `The read and write sides of a sync.RWMutex must be paired: RUnlock of a
+write lock, or Unlock of a read lock, panics or corrupts the mutex's
+counts, and an RUnlock on a path that never read-locked does the same.
+Holding a read lock while writing a field of the struct it protects lets
+other readers run concurrently with the write. None of these fail to
+compile, and on quiet paths they may not fail at all.`,
+	Bad: `func (c *Cache) Touch(key string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.hits[key]++ // writes under a read lock
+}`,
+	Good: `func (c *Cache) Touch(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hits[key]++
+}`,
+	Run: func(pass *Pass) {
+		// Annotated fields are guarded-by's to report.
+		guards := collectGuards(pass, func(*ast.Comment, string) {})
+		for _, body := range funcBodies(pass) {
+			flow := newLockFlow(pass, body)
+			readLocked := map[string]bool{}
+			flow.each(func(op lockOp, _ lockState) {
+				if op.method == "RLock" {
+					readLocked[op.mutex] = true
+				}
+			})
+			flow.each(func(op lockOp, state lockState) {
+				if op.locks() {
+					return
+				}
+				held, ok := state.must[op.mutex]
+				switch {
+				case ok && held.lock.unlockMethod() != op.method:
+					finding := pass.NewFinding(op.call, nil, "%s was locked with %s but is released with %s", op.mutex, held.lock.method, op.method)
+					finding.Relate(held.lock.call, "%s locked with %s here", op.mutex, held.lock.method)
+					pass.ReportFinding(finding)
+				case op.method == "RUnlock" && readLocked[op.mutex]:
+					if _, ok := state.may[op.mutex]; !ok {
+						pass.Reportf(op.call, nil, "RUnlock of %s on a path that does not read-lock it", op.mutex)
+					}
+				}
+			})
+			flow.nodes(func(n ast.Node, state lockState) {
+				var written []ast.Expr
+				switch n := n.(type) {
+				case *ast.AssignStmt:
+					written = n.Lhs
+				case *ast.IncDecStmt:
+					written = []ast.Expr{n.X}
+				}
+				for _, lhs := range written {
+					sel, ok := writtenExpr(lhs).(*ast.SelectorExpr)
+					if !ok {
+						continue
+					}
+					selection := pass.TypesInfo.Selections[sel]
+					if selection == nil || selection.Kind() != types.FieldVal || guards[selection.Obj()].name != "" {
+						continue
+					}
+					if mutex, ok := readLockOnly(state, stringifyNode(pass.Fset, sel.X)); ok {
+						pass.Reportf(lhs, nil, "writing %s while holding only a read lock on %s", stringifyNode(pass.Fset, sel), mutex)
+					}
+				}
+			})
+		}
+	},
+}
+
+// readLockOnly returns the mutex of the struct x, embedded in it or one of
+// its fields, that state holds read-locked, if it holds none of them
+// write-locked.
+func readLockOnly(state lockState, x string) (string, bool) {
+	read := ""
+	for mutex, held := range state.must {
+		field, ok := strings.CutPrefix(mutex, x+".")
+		if mutex != x && (!ok || strings.ContainsAny(field, ".[(")) {
+			continue
+		}
+		if held.lock.method == "Lock" {
+			return "", false
+		}
+		read = mutex
+	}
+	return read, read != ""
+}
+
+var lockAcrossChan = &Check{
+	Name: "lock-across-chan",
+	ID:   "TS0049",
+	Doc:  "report channel sends and receives made while holding a mutex",
+	Rationale: `A channel operation blocks until a peer is ready. Waiting for it with a
+mutex held keeps every other goroutine needing the mutex waiting too, and
+when the peer is one of them, neither can proceed: a deadlock that only
+shows under load. Release the mutex before the operation, or move the
+operation out of the critical section. Selects with a default case never
+block and are not reported.`,
+	Bad: `c.mu.Lock()
+defer c.mu.Unlock()
+c.pending++
+c.events <- ev // blocks with c.mu held`,
+	Good: `c.mu.Lock()
+c.pending++
+c.mu.Unlock()
+c.events <- ev`,
+	Run: func(pass *Pass) {
+		// nonBlocking holds the communications of selects with a default
+		// case; ranged holds the channels of range loops.
+		nonBlocking := map[ast.Stmt]bool{}
+		ranged := map[ast.Expr]bool{}
+		pass.Inspector.Preorder([]ast.Node{(*ast.SelectStmt)(nil), (*ast.RangeStmt)(nil)}, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.SelectStmt:
+				var comms []ast.Stmt
+				hasDefault := false
+				for _, clause := range n.Body.List {
+					comm := clause.(*ast.CommClause).Comm
+					hasDefault = hasDefault || comm == nil
+					comms = append(comms, comm)
+				}
+				for _, comm := range comms {
+					if comm != nil {
+						nonBlocking[comm] = hasDefault
+					}
+				}
+			case *ast.RangeStmt:
+				if isChan(pass.TypesInfo.TypeOf(n.X)) {
+					ranged[n.X] = true
+				}
+			}
+		})
+		for _, body := range funcBodies(pass) {
+			flow := newLockFlow(pass, body)
+			flow.nodes(func(n ast.Node, state lockState) {
+				if len(state.must) == 0 {
+					return
+				}
+				if stmt, ok := n.(ast.Stmt); ok && nonBlocking[stmt] {
+					return
+				}
+				report := func(node ast.Node, what string, ch ast.Expr) {
+					held := firstHeld(state.must)
+					finding := pass.NewFinding(node, nil, "%s %s while holding %s", what, stringifyNode(pass.Fset, ch), held.lock.mutex)
+					finding.Relate(held.lock.call, "%s locked here", held.lock.mutex)
+					pass.ReportFinding(finding)
+				}
+				if expr, ok := n.(ast.Expr); ok && ranged[expr] {
+					report(expr, "ranging over", expr)
+					return
+				}
+				ast.Inspect(n, func(n ast.Node) bool {
+					switch n := n.(type) {
+					case *ast.FuncLit:
+						return false
+					case *ast.SendStmt:
+						report(n, "sending on", n.Chan)
+					case *ast.UnaryExpr:
+						if n.Op == token.ARROW {
+							report(n, "receiving from", n.X)
+						}
+					}
+					return true
+				})
+			})
+		}
+	},
+}
+
+// firstHeld returns the lock of set acquired first in the source.
+func firstHeld(set lockSet) heldLock {
+	var first heldLock
+	for _, held := range set {
+		if first.lock.call == nil || held.lock.call.Pos() < first.lock.call.Pos() {
+			first = held
+		}
+	}
+	return first
+}
+
+// blockingFuncs lists the functions and methods, by the full name
+// types.Func.FullName gives them, that may block for a long time waiting on
+// the network, the file system, another process or the clock.
+// Config.BlockingFuncs adds to the list.
+var blockingFuncs = []string{
+	"time.Sleep",
+	"net.Dial",
+	"net.DialTimeout",
+	"(*net.Dialer).Dial",
+	"(*net.Dialer).DialContext",
+	"(net.Listener).Accept",
+	"(net.Conn).Read",
+	"(net.Conn).Write",
+	"net/http.Get",
+	"net/http.Head",
+	"net/http.Post",
+	"net/http.PostForm",
+	"(*net/http.Client).Do",
+	"(*net/http.Client).Get",
+	"(*net/http.Client).Head",
+	"(*net/http.Client).Post",
+	"(*net/http.Client).PostForm",
+	"os.Open",
+	"os.OpenFile",
+	"os.Create",
+	"os.ReadFile",
+	"os.WriteFile",
+	"(*os.File).Read",
+	"(*os.File).ReadAt",
+	"(*os.File).Write",
+	"(*os.File).WriteAt",
+	"(*os.File).WriteString",
+	"(*os.File).Sync",
+	"io.ReadAll",
+	"io.ReadFull",
+	"io.Copy",
+	"io.CopyN",
+	"(*os/exec.Cmd).Run",
+	"(*os/exec.Cmd).Wait",
+	"(*os/exec.Cmd).Output",
+	"(*os/exec.Cmd).CombinedOutput",
+}
+
+var lockAcrossBlocking = &Check{
+	Name: "lock-across-blocking",
+	ID:   "TS0050",
+	Doc:  "report calls that may block for a long time made while holding a mutex",
+	Rationale: `Network and file I/O, sleeping and waiting for a process take far longer
+than the memory accesses a mutex is meant to guard. Every goroutine needing
+the mutex waits for them too, serializing the program on one slow call.
+Functions of the package calling such functions are reported as well. Do
+the slow work before locking or after unlocking, and hold the mutex only to
+read or publish its result.`,
+	Bad: `c.mu.Lock()
+defer c.mu.Unlock()
+resp, err := http.Get(c.url)
+...
+c.cached = body`,
+	Good: `resp, err := http.Get(c.url)
+...
+c.mu.Lock()
+c.cached = body
+c.mu.Unlock()`,
+	Run: func(pass *Pass) {
+		isBlocking := func(fn *types.Func) bool {
+			return pass.blockingFuncs[fn.FullName()]
+		}
+		blocking := callingFuncs(pass, isBlocking)
+		for _, body := range funcBodies(pass) {
+			flow := newLockFlow(pass, body)
+			flow.nodes(func(n ast.Node, state lockState) {
+				if len(state.must) == 0 {
+					return
+				}
+				ast.Inspect(n, func(n ast.Node) bool {
+					switch n := n.(type) {
+					case *ast.FuncLit, *ast.GoStmt, *ast.DeferStmt:
+						return false
+					case *ast.CallExpr:
+						callee, ok := calledFunc(pass.TypesInfo, n)
+						if !ok || !isBlocking(callee) && !blocking[callee] {
+							break
+						}
+						what := ""
+						if !isBlocking(callee) {
+							what = ", which may block,"
+						}
+						held := firstHeld(state.must)
+						finding := pass.NewFinding(n, nil, "calling %s%s while holding %s", stringifyNode(pass.Fset, n.Fun), what, held.lock.mutex)
+						finding.Relate(held.lock.call, "%s locked here", held.lock.mutex)
+						pass.ReportFinding(finding)
+					}
+					return true
+				})
+			})
+		}
+	},
+}
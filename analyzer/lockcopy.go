@@ -0,0 +1,213 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+var lockCopy = &Check{
+	Name: "lock-copy",
+	ID:   "TS0041",
+	Doc:  "report values containing a lock copied by assignments, calls, returns, range loops and composite literals, including channel payloads",
+	Rationale: `Copying a struct that contains a sync.Mutex, sync.RWMutex or any other type
+following the noCopy convention copies the lock with it: the copy is locked
+or unlocked independently of the original, and code locking one no longer
+excludes code locking the other. go vet's copylocks check reports most of
+these copies too; this check reports them alongside the other findings, and
+singles out the values copied into payloads built for channels, where the
+copy leaves for another goroutine.`,
+	Bad: `type account struct {
+	mu      sync.Mutex
+	balance int
+}
+
+updates <- &update{account: acct} // copies acct.mu`,
+	Good: `type update struct {
+	account *account
+}
+
+updates <- &update{account: &acct}`,
+	Run: func(pass *Pass) {
+		// payloads holds the composite literals built as values sent over
+		// channels.
+		payloads := map[*ast.CompositeLit]bool{}
+		pass.Inspector.Preorder([]ast.Node{(*ast.SendStmt)(nil)}, func(n ast.Node) {
+			ast.Inspect(n.(*ast.SendStmt).Value, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncLit:
+					return false
+				case *ast.CompositeLit:
+					payloads[n] = true
+				}
+				return true
+			})
+		})
+		check := func(expr ast.Expr, what string) {
+			if t := copiedLock(pass.TypesInfo, expr); t != nil {
+				pass.Reportf(expr, t, "%s copies a value containing a lock", what)
+			}
+		}
+		pass.Inspector.Preorder([]ast.Node{(*ast.AssignStmt)(nil), (*ast.ValueSpec)(nil), (*ast.CallExpr)(nil), (*ast.ReturnStmt)(nil), (*ast.RangeStmt)(nil), (*ast.CompositeLit)(nil)}, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				if len(n.Lhs) != len(n.Rhs) {
+					break
+				}
+				for i, rhs := range n.Rhs {
+					if id, ok := n.Lhs[i].(*ast.Ident); !ok || id.Name != "_" {
+						check(rhs, "assignment")
+					}
+				}
+			case *ast.ValueSpec:
+				for _, value := range n.Values {
+					check(value, "declaration")
+				}
+			case *ast.CallExpr:
+				if pass.TypesInfo.Types[n.Fun].IsType() {
+					// The conversion is reported where its result is copied.
+					break
+				}
+				if id, ok := ast.Unparen(n.Fun).(*ast.Ident); ok && id.Name != "append" {
+					if _, ok := pass.TypesInfo.Uses[id].(*types.Builtin); ok {
+						break
+					}
+				}
+				for _, arg := range n.Args {
+					if findType(pass.TypesInfo.TypeOf(arg), isWaitGroup) != nil {
+						// waitgroup-by-value reports it.
+						continue
+					}
+					check(arg, "call")
+				}
+			case *ast.ReturnStmt:
+				for _, result := range n.Results {
+					check(result, "return")
+				}
+			case *ast.RangeStmt:
+				if id, ok := n.Value.(*ast.Ident); n.Value == nil || ok && id.Name == "_" {
+					break
+				}
+				if t := findType(pass.TypesInfo.TypeOf(n.Value), isLock); t != nil {
+					pass.Reportf(n.Value, t, "range loop copies a value containing a lock")
+				}
+			case *ast.CompositeLit:
+				what := "composite literal"
+				if payloads[n] {
+					what = "channel payload"
+				}
+				for _, elt := range n.Elts {
+					if kv, ok := elt.(*ast.KeyValueExpr); ok {
+						elt = kv.Value
+					}
+					check(elt, what)
+				}
+			}
+		})
+	},
+}
+
+// copiedLock returns the lock within the value of expr if evaluating expr
+// where a value is expected copies an existing one, or nil. Composite
+// literals and the results of function calls are fresh values, and their
+// locks unused.
+func copiedLock(info *types.Info, expr ast.Expr) types.Type {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.CompositeLit:
+		return nil
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return nil
+		}
+	case *ast.CallExpr:
+		if !info.Types[e.Fun].IsType() || len(e.Args) != 1 {
+			return nil
+		}
+		// Conversions copy their operand.
+		if copiedLock(info, e.Args[0]) == nil {
+			return nil
+		}
+	}
+	return findType(info.TypeOf(expr), isLock)
+}
+
+var lockByValue = &Check{
+	Name: "lock-by-value",
+	ID:   "TS0042",
+	Doc:  "report methods with value receivers and functions returning values of types containing a lock, naming the field holding it",
+	Rationale: `A method with a value receiver works on a copy of its receiver, and a
+function returning a struct by value hands out a copy of it. When the type
+holds a sync.Mutex, the copy holds a lock of its own: locking it excludes
+nobody else, and the fields it should protect are unguarded. The type is
+meant to be used through a pointer; receivers and results should say so.`,
+	Bad: `func (c Cache) Get(key string) string {
+	c.mu.Lock() // locks a copy
+	defer c.mu.Unlock()
+	return c.m[key]
+}`,
+	Good: `func (c *Cache) Get(key string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.m[key]
+}`,
+	Run: func(pass *Pass) {
+		for _, f := range pass.Files {
+			for _, decl := range f.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				if fd.Recv != nil && len(fd.Recv.List) == 1 {
+					recv := fd.Recv.List[0]
+					if path := lockPath(pass.TypesInfo.TypeOf(recv.Type)); path != "" {
+						pass.Reportf(recv.Type, nil, "method %s has a value receiver, so it works on a copy of the lock %s", fd.Name.Name, path)
+					}
+				}
+				if fd.Type.Results == nil {
+					continue
+				}
+				for _, result := range fd.Type.Results.List {
+					if path := lockPath(pass.TypesInfo.TypeOf(result.Type)); path != "" {
+						pass.Reportf(result.Type, nil, "%s returns a copy of the lock %s; return a pointer instead", fd.Name.Name, path)
+					}
+				}
+			}
+		}
+	},
+}
+
+// lockPath returns the path, through struct fields and array elements but
+// not pointers, to the first lock within values of t, such as "mu" or
+// "state.mu", or "" if they contain none or t is itself a lock.
+func lockPath(t types.Type) string {
+	return typePath(t, isLock)
+}
+
+// typePath returns the path, through struct fields and array elements but
+// not pointers, to the first type within t for which match is true, or ""
+// if there is none or t is itself one.
+func typePath(t types.Type, match func(types.Type) bool) string {
+	if t == nil {
+		return ""
+	}
+	switch u := types.Unalias(t).Underlying().(type) {
+	case *types.Array:
+		if match(u.Elem()) {
+			return "[i]"
+		}
+		if path := typePath(u.Elem(), match); path != "" {
+			return "[i]" + path
+		}
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			field := u.Field(i)
+			if match(field.Type()) {
+				return field.Name()
+			}
+			if path := typePath(field.Type(), match); path != "" {
+				return field.Name() + "." + path
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,295 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/cfg"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// A lockOp is a call locking or unlocking a mutex.
+type lockOp struct {
+	call *ast.CallExpr
+	// mutex is the source of the locked expression, e.g. "c.mu".
+	mutex string
+	// method is "Lock", "RLock", "Unlock" or "RUnlock".
+	method string
+	// deferred is set when the call is deferred, directly or in a deferred
+	// function literal.
+	deferred bool
+}
+
+// locks reports whether the operation acquires the mutex.
+func (op lockOp) locks() bool {
+	return op.method == "Lock" || op.method == "RLock"
+}
+
+// unlockMethod returns the method releasing the mutex locked by a Lock or
+// RLock operation.
+func (op lockOp) unlockMethod() string {
+	if op.method == "RLock" {
+		return "RUnlock"
+	}
+	return "Unlock"
+}
+
+// A heldLock is a mutex held at some point of a function.
+type heldLock struct {
+	// lock is the call that locked it, Lock or RLock.
+	lock lockOp
+	// deferred is set when an unlock of the mutex has been deferred since,
+	// on every path in a may set.
+	deferred bool
+}
+
+// A lockSet maps the mutexes held, by source, to how they are held.
+type lockSet map[string]heldLock
+
+func (s lockSet) clone() lockSet {
+	c := make(lockSet, len(s))
+	for k, v := range s {
+		c[k] = v
+	}
+	return c
+}
+
+func (s lockSet) equal(other lockSet) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for k, v := range s {
+		if w, ok := other[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+// A lockState is the set of mutexes held on some path to a point of a
+// function, may, and on every path to it, must.
+type lockState struct {
+	may, must lockSet
+}
+
+// apply returns the state after op.
+func (s lockState) apply(op lockOp) lockState {
+	s = lockState{may: s.may.clone(), must: s.must.clone()}
+	switch {
+	case op.deferred && !op.locks():
+		for _, set := range []lockSet{s.may, s.must} {
+			if held, ok := set[op.mutex]; ok {
+				held.deferred = true
+				set[op.mutex] = held
+			}
+		}
+	case op.deferred:
+	case op.locks():
+		s.may[op.mutex] = heldLock{lock: op}
+		s.must[op.mutex] = heldLock{lock: op}
+	default:
+		delete(s.may, op.mutex)
+		delete(s.must, op.mutex)
+	}
+	return s
+}
+
+// merge returns the state at a point reached from both s and other.
+func (s lockState) merge(other lockState) lockState {
+	merged := lockState{may: s.may.clone(), must: lockSet{}}
+	for k, v := range other.may {
+		if w, ok := merged.may[k]; ok {
+			w.deferred = w.deferred && v.deferred
+			merged.may[k] = w
+		} else {
+			merged.may[k] = v
+		}
+	}
+	for k, v := range s.must {
+		if w, ok := other.must[k]; ok {
+			v.deferred = v.deferred && w.deferred
+			merged.must[k] = v
+		}
+	}
+	return merged
+}
+
+// A lockFlow is the flow of mutexes held through a function body, outside
+// the function literals it contains, which run at other times.
+type lockFlow struct {
+	cfg *cfg.CFG
+	// ops holds the lock operations of each node of the graph, in order.
+	ops map[ast.Node][]lockOp
+	// in holds the state on entry to each reachable block.
+	in map[*cfg.Block]lockState
+}
+
+// newLockFlow computes the mutexes held at each point of body.
+func newLockFlow(pass *Pass, body *ast.BlockStmt) *lockFlow {
+	mayReturn := func(call *ast.CallExpr) bool {
+		if id, ok := ast.Unparen(call.Fun).(*ast.Ident); ok && id.Name == "panic" {
+			if _, ok := pass.TypesInfo.Uses[id].(*types.Builtin); ok {
+				return false
+			}
+		}
+		callee := typeutil.StaticCallee(pass.TypesInfo, call)
+		return callee == nil || !exitFuncs[callee.FullName()]
+	}
+	flow := &lockFlow{
+		cfg: cfg.New(body, mayReturn),
+		ops: map[ast.Node][]lockOp{},
+		in:  map[*cfg.Block]lockState{},
+	}
+	for _, b := range flow.cfg.Blocks {
+		for _, n := range b.Nodes {
+			if ops := lockOps(pass, n); len(ops) > 0 {
+				flow.ops[n] = ops
+			}
+		}
+	}
+	entry := flow.cfg.Blocks[0]
+	flow.in[entry] = lockState{may: lockSet{}, must: lockSet{}}
+	work := []*cfg.Block{entry}
+	for len(work) > 0 {
+		b := work[len(work)-1]
+		work = work[:len(work)-1]
+		out := flow.out(b)
+		for _, succ := range b.Succs {
+			next := out
+			if in, ok := flow.in[succ]; ok {
+				if next = in.merge(out); next.may.equal(in.may) && next.must.equal(in.must) {
+					continue
+				}
+			}
+			flow.in[succ] = next
+			work = append(work, succ)
+		}
+	}
+	return flow
+}
+
+// out returns the state on leaving b.
+func (f *lockFlow) out(b *cfg.Block) lockState {
+	state := f.in[b]
+	for _, n := range b.Nodes {
+		for _, op := range f.ops[n] {
+			state = state.apply(op)
+		}
+	}
+	return state
+}
+
+// each calls visit for every lock operation of the function, with the state
+// before it.
+func (f *lockFlow) each(visit func(op lockOp, state lockState)) {
+	f.nodes(func(n ast.Node, state lockState) {
+		for _, op := range f.ops[n] {
+			visit(op, state)
+			state = state.apply(op)
+		}
+	})
+}
+
+// nodes calls visit for every reachable node of the graph, with the state
+// before it.
+func (f *lockFlow) nodes(visit func(n ast.Node, state lockState)) {
+	for _, b := range f.cfg.Blocks {
+		state, ok := f.in[b]
+		if !ok {
+			continue
+		}
+		for _, n := range b.Nodes {
+			visit(n, state)
+			for _, op := range f.ops[n] {
+				state = state.apply(op)
+			}
+		}
+	}
+}
+
+// exits calls visit for every return statement and call of panic ending the
+// function, with the state there.
+func (f *lockFlow) exits(visit func(exit ast.Node, state lockState)) {
+	for _, b := range f.cfg.Blocks {
+		if _, ok := f.in[b]; !ok || len(b.Succs) > 0 || len(b.Nodes) == 0 {
+			continue
+		}
+		switch last := b.Nodes[len(b.Nodes)-1].(type) {
+		case *ast.ReturnStmt:
+			visit(last, f.out(b))
+		case *ast.ExprStmt:
+			if call, ok := last.X.(*ast.CallExpr); ok {
+				if id, ok := ast.Unparen(call.Fun).(*ast.Ident); ok && id.Name == "panic" {
+					visit(last, f.out(b))
+				}
+			}
+		}
+	}
+}
+
+// lockOps returns the lock operations of n in source order, outside function
+// literals other than deferred ones.
+func lockOps(pass *Pass, n ast.Node) []lockOp {
+	var ops []lockOp
+	deferred := false
+	var inspect func(n ast.Node) bool
+	inspect = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			deferred = true
+			if lit, ok := ast.Unparen(n.Call.Fun).(*ast.FuncLit); ok {
+				ast.Inspect(lit.Body, inspect)
+			}
+		case *ast.CallExpr:
+			if op, ok := lockCall(pass, n); ok {
+				op.deferred = deferred
+				ops = append(ops, op)
+			}
+		}
+		return true
+	}
+	ast.Inspect(n, inspect)
+	return ops
+}
+
+// lockCall returns the lock operation call performs, if it calls a Lock,
+// RLock, Unlock or RUnlock method taking and returning nothing.
+func lockCall(pass *Pass, call *ast.CallExpr) (lockOp, bool) {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return lockOp{}, false
+	}
+	switch sel.Sel.Name {
+	case "Lock", "RLock", "Unlock", "RUnlock":
+	default:
+		return lockOp{}, false
+	}
+	fn, ok := calledFunc(pass.TypesInfo, call)
+	if !ok {
+		return lockOp{}, false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() == nil || sig.Params().Len() > 0 || sig.Results().Len() > 0 {
+		return lockOp{}, false
+	}
+	return lockOp{call: call, mutex: stringifyNode(pass.Fset, sel.X), method: sel.Sel.Name}, true
+}
+
+// funcBodies returns the bodies of the functions declared in the package and
+// of the function literals within them.
+func funcBodies(pass *Pass) []*ast.BlockStmt {
+	var bodies []*ast.BlockStmt
+	pass.Inspector.Preorder([]ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)}, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.FuncDecl:
+			if n.Body != nil {
+				bodies = append(bodies, n.Body)
+			}
+		case *ast.FuncLit:
+			bodies = append(bodies, n.Body)
+		}
+	})
+	return bodies
+}
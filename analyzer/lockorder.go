@@ -0,0 +1,407 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+var lockOrder = &Check{
+	Name: "lock-order",
+	ID:   "TS0051",
+	Doc:  "report mutexes acquired in inconsistent orders across the package, which can deadlock",
+	Rationale: `A goroutine holding mutex A while acquiring B and another holding B while
+acquiring A can each wait for the other forever, the ABBA deadlock. Only an
+unlucky interleaving triggers it, so it rarely shows in tests. Mutexes are
+told apart by the type and field holding them, or by package variable, and
+acquisitions include those made by the functions of the package called
+while a mutex is held. Pick one order and acquire the mutexes in it
+everywhere.`,
+	Bad: `func (b *Bank) Audit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ledger.mu.Lock() // Bank.mu, then Ledger.mu
+	...
+}
+
+func (l *Ledger) Post(b *Bank) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b.mu.Lock() // Ledger.mu, then Bank.mu
+	...
+}`,
+	Good: `func (l *Ledger) Post(b *Bank) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	l.mu.Lock() // Bank.mu, then Ledger.mu, as in Audit
+	...
+}`,
+	Run: func(pass *Pass) {
+		acquires := collectAcquisitions(pass)
+		edges := map[string]map[string]*lockEdge{}
+		addEdge := func(held heldLock, mutex string, edge *lockEdge) {
+			from, ok := lockClass(pass, held.lock.call)
+			if !ok || from == mutex {
+				return
+			}
+			if edges[from] == nil {
+				edges[from] = map[string]*lockEdge{}
+			}
+			if edges[from][mutex] == nil {
+				edge.from, edge.to, edge.held = from, mutex, held.lock.call
+				edges[from][mutex] = edge
+			}
+		}
+		for _, body := range funcBodies(pass) {
+			flow := newLockFlow(pass, body)
+			flow.each(func(op lockOp, state lockState) {
+				if !op.locks() || op.deferred {
+					return
+				}
+				mutex, ok := lockClass(pass, op.call)
+				if !ok {
+					return
+				}
+				for _, held := range state.must {
+					addEdge(held, mutex, &lockEdge{node: op.call})
+				}
+			})
+			flow.nodes(func(n ast.Node, state lockState) {
+				if len(state.must) == 0 {
+					return
+				}
+				ast.Inspect(n, func(n ast.Node) bool {
+					switch n := n.(type) {
+					case *ast.FuncLit, *ast.GoStmt, *ast.DeferStmt:
+						return false
+					case *ast.CallExpr:
+						for _, acq := range callAcquisitions(pass, n, acquires) {
+							for _, held := range state.must {
+								addEdge(held, acq.mutex, &lockEdge{node: n, via: stringifyNode(pass.Fset, n.Fun), site: acq.call})
+							}
+						}
+					}
+					return true
+				})
+			})
+		}
+		reportLockCycles(pass, edges)
+	},
+}
+
+// A lockEdge records a mutex, to, acquired while another, from, is held.
+type lockEdge struct {
+	from, to string
+	// held is the call locking from.
+	held *ast.CallExpr
+	// node is the call acquiring to: its Lock call, or the call of via, a
+	// function acquiring it at site.
+	node ast.Node
+	via  string
+	site *ast.CallExpr
+}
+
+// describe returns a description of the acquisition of e.to.
+func (e *lockEdge) describe() string {
+	if e.via != "" {
+		return fmt.Sprintf("calling %s, which acquires %s,", e.via, e.to)
+	}
+	return "acquiring " + e.to
+}
+
+// relate adds the places of e to finding.
+func (e *lockEdge) relate(finding *Finding) {
+	finding.Relate(e.held, "%s locked here", e.from)
+	if e.node.Pos() != finding.Pos {
+		finding.Relate(e.node, "%s acquired here while holding %s", e.to, e.from)
+	}
+	if e.via != "" {
+		finding.Relate(e.site, "%s locked here by %s", e.to, e.via)
+	}
+}
+
+// reportLockCycles reports each cycle of edges once, at the first of its
+// edges in the order of the mutexes.
+func reportLockCycles(pass *Pass, edges map[string]map[string]*lockEdge) {
+	var mutexes []string
+	for from := range edges {
+		mutexes = append(mutexes, from)
+	}
+	slices.Sort(mutexes)
+	seen := map[string]bool{}
+	for _, from := range mutexes {
+		var succs []string
+		for to := range edges[from] {
+			succs = append(succs, to)
+		}
+		slices.Sort(succs)
+		for _, to := range succs {
+			path := cyclePath(edges, to, from)
+			if path == nil {
+				continue
+			}
+			cycle := append([]string{from}, path...)
+			key := canonicalCycle(cycle)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			edge := edges[from][to]
+			if len(path) == 2 {
+				back := edges[to][from]
+				finding := pass.NewFinding(edge.node, nil, "%s while holding %s, but at %s, %s while holding %s; goroutines taking both orders can deadlock", edge.describe(), from, shortPosition(pass.Fset, back.node.Pos()), back.describe(), to)
+				finding.StableMessage = fmt.Sprintf("%s while holding %s, but elsewhere, %s while holding %s; goroutines taking both orders can deadlock", edge.describe(), from, back.describe(), to)
+				edge.relate(finding)
+				back.relate(finding)
+				pass.ReportFinding(finding)
+				continue
+			}
+			var sites []string
+			for i := 1; i < len(cycle)-1; i++ {
+				sites = append(sites, shortPosition(pass.Fset, edges[cycle[i]][cycle[i+1]].node.Pos()))
+			}
+			finding := pass.NewFinding(edge.node, nil, "%s while holding %s closes the lock cycle %s, whose other acquisitions are at %s; goroutines taking them in turn can deadlock", edge.describe(), from, strings.Join(cycle, " -> "), strings.Join(sites, ", "))
+			finding.StableMessage = fmt.Sprintf("%s while holding %s closes the lock cycle %s; goroutines taking them in turn can deadlock", edge.describe(), from, strings.Join(cycle, " -> "))
+			for i := 0; i < len(cycle)-1; i++ {
+				edges[cycle[i]][cycle[i+1]].relate(finding)
+			}
+			pass.ReportFinding(finding)
+		}
+	}
+}
+
+// shortPosition formats pos as the base name of its file and its line, for
+// messages pointing at another place.
+func shortPosition(fset *token.FileSet, pos token.Pos) string {
+	position := fset.Position(pos)
+	return fmt.Sprintf("%s:%d", filepath.Base(position.Filename), position.Line)
+}
+
+// cyclePath returns the shortest path of edges from one mutex to another,
+// starting after from and ending with to, or nil if there is none.
+func cyclePath(edges map[string]map[string]*lockEdge, from, to string) []string {
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		at := queue[0]
+		queue = queue[1:]
+		if at == to {
+			path := []string{at}
+			for at != from {
+				at = prev[at]
+				path = append(path, at)
+			}
+			slices.Reverse(path)
+			return path
+		}
+		var succs []string
+		for next := range edges[at] {
+			succs = append(succs, next)
+		}
+		slices.Sort(succs)
+		for _, next := range succs {
+			if _, ok := prev[next]; !ok {
+				prev[next] = at
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
+
+// canonicalCycle returns a key identifying the cycle of mutexes whatever
+// mutex it is listed from. The last mutex of cycle repeats the first.
+func canonicalCycle(cycle []string) string {
+	nodes := cycle[:len(cycle)-1]
+	first := 0
+	for i, m := range nodes {
+		if m < nodes[first] {
+			first = i
+		}
+	}
+	return strings.Join(append(slices.Clone(nodes[first:]), nodes[:first]...), " -> ")
+}
+
+// An acquisition is a mutex a function locks, by class, and the call
+// locking it.
+type acquisition struct {
+	mutex string
+	call  *ast.CallExpr
+}
+
+// collectAcquisitions returns the mutexes each function declared in the
+// package locks on its own goroutine, directly or through the functions it
+// calls.
+func collectAcquisitions(pass *Pass) map[*types.Func][]acquisition {
+	bodies := map[*types.Func]*ast.BlockStmt{}
+	acquires := map[*types.Func][]acquisition{}
+	for _, f := range pass.Files {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Body == nil {
+				continue
+			}
+			fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func)
+			if !ok {
+				continue
+			}
+			bodies[fn] = fd.Body
+			for _, op := range lockOps(pass, fd.Body) {
+				if mutex, ok := lockClass(pass, op.call); ok && op.locks() && !op.deferred {
+					acquires[fn] = addAcquisition(acquires[fn], acquisition{mutex, op.call})
+				}
+			}
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for fn, body := range bodies {
+			ast.Inspect(body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncLit, *ast.GoStmt, *ast.DeferStmt:
+					return false
+				case *ast.CallExpr:
+					for _, acq := range callAcquisitions(pass, n, acquires) {
+						if updated := addAcquisition(acquires[fn], acq); len(updated) > len(acquires[fn]) {
+							acquires[fn] = updated
+							changed = true
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+	return acquires
+}
+
+// addAcquisition adds acq to list unless its mutex is already there.
+func addAcquisition(list []acquisition, acq acquisition) []acquisition {
+	for _, other := range list {
+		if other.mutex == acq.mutex {
+			return list
+		}
+	}
+	return append(list, acq)
+}
+
+// callAcquisitions returns the mutexes call acquires in the function it
+// calls: those of acquires for a function of the package, and those its fact
+// says a method of another package locks, at the call.
+func callAcquisitions(pass *Pass, call *ast.CallExpr, acquires map[*types.Func][]acquisition) []acquisition {
+	if _, ok := lockCall(pass, call); ok {
+		return nil
+	}
+	fn, ok := calledFunc(pass.TypesInfo, call)
+	if !ok {
+		return nil
+	}
+	if fn.Pkg() == pass.Pkg {
+		return acquires[fn]
+	}
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	fact := pass.facts.funcFact(fn)
+	if !ok || fact == nil {
+		return nil
+	}
+	var list []acquisition
+	for _, path := range fact.LockedFields {
+		if mutex, ok := fieldClass(pass, pass.TypesInfo.TypeOf(sel.X), path); ok {
+			list = addAcquisition(list, acquisition{mutex, call})
+		}
+	}
+	return list
+}
+
+// lockClass returns the class of the mutex a Lock or RLock call locks: the
+// named type and field holding it, such as "Account.mu", the named type for
+// a mutex it embeds, or the package variable. Mutexes in local variables
+// have no class.
+func lockClass(pass *Pass, call *ast.CallExpr) (string, bool) {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	return mutexClass(pass, sel.X)
+}
+
+// mutexClass returns the class of the mutex expr denotes, as lockClass.
+func mutexClass(pass *Pass, expr ast.Expr) (string, bool) {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.StarExpr:
+		return mutexClass(pass, e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.AND {
+			return mutexClass(pass, e.X)
+		}
+	case *ast.SelectorExpr:
+		if sel := pass.TypesInfo.Selections[e]; sel != nil {
+			if sel.Kind() != types.FieldVal {
+				return "", false
+			}
+			if class, ok := fieldClass(pass, pass.TypesInfo.TypeOf(e.X), e.Sel.Name); ok {
+				return class, true
+			}
+			if class, ok := mutexClass(pass, e.X); ok {
+				return class + "." + e.Sel.Name, true
+			}
+			return "", false
+		}
+		return mutexClass(pass, e.Sel)
+	case *ast.Ident:
+		v, ok := pass.TypesInfo.Uses[e].(*types.Var)
+		if !ok {
+			return "", false
+		}
+		if v.Parent() == v.Pkg().Scope() {
+			if v.Pkg() != pass.Pkg {
+				return v.Pkg().Name() + "." + v.Name(), true
+			}
+			return v.Name(), true
+		}
+		if isMutex(v.Type()) {
+			return "", false
+		}
+		return fieldClass(pass, v.Type(), "")
+	}
+	return "", false
+}
+
+// fieldClass returns the class of the mutex at path, a field path as in
+// funcFact.LockedFields, from a value of type t, if t is a named type or a
+// pointer to one.
+func fieldClass(pass *Pass, t types.Type, path string) (string, bool) {
+	fields := strings.Split(path, ".")
+	last := fields[len(fields)-1]
+	for _, name := range fields[:len(fields)-1] {
+		obj, _, _ := types.LookupFieldOrMethod(t, false, pass.Pkg, name)
+		field, ok := obj.(*types.Var)
+		if !ok {
+			return "", false
+		}
+		t = field.Type()
+	}
+	if ptr, ok := types.Unalias(t).(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || last == "" && isMutex(named) {
+		return "", false
+	}
+	return joinPath(types.TypeString(named.Origin(), classQualifier(pass)), last), true
+}
+
+// classQualifier qualifies the types of another package in mutex classes by
+// package name.
+func classQualifier(pass *Pass) types.Qualifier {
+	return func(p *types.Package) string {
+		if p == pass.Pkg {
+			return ""
+		}
+		return p.Name()
+	}
+}
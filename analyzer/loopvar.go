@@ -0,0 +1,181 @@
+package analyzer
+
+import (
+	"bytes"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"go/version"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+var loopVarCapture = &Check{
+	Name: "loop-var-capture",
+	ID:   "TS0006",
+	Doc:  "report goroutines capturing a loop variable shared by all iterations, in code targeting Go before 1.22",
+	Rationale: `Before Go 1.22, the variables declared by a for or range clause were shared
+by all iterations. A goroutine capturing one sees whatever value the loop
+has moved on to, and reads it while the loop writes it.`,
+	Bad: `for _, job := range jobs {
+	go func() {
+		job.Run() // may run the same job twice
+	}()
+}`,
+	Good: `for _, job := range jobs {
+	job := job
+	go func() {
+		job.Run()
+	}()
+}`,
+	Run: func(pass *Pass) {
+		for _, f := range pass.Files {
+			if !sharesLoopVars(pass, f) {
+				continue
+			}
+			ast.Inspect(f, func(n ast.Node) bool {
+				var body *ast.BlockStmt
+				vars := map[types.Object]bool{}
+				switch n := n.(type) {
+				case *ast.ForStmt:
+					body = n.Body
+					if init, ok := n.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE {
+						for _, lhs := range init.Lhs {
+							addLoopVar(pass.TypesInfo, vars, lhs)
+						}
+					}
+				case *ast.RangeStmt:
+					body = n.Body
+					if n.Tok == token.DEFINE {
+						addLoopVar(pass.TypesInfo, vars, n.Key)
+						addLoopVar(pass.TypesInfo, vars, n.Value)
+					}
+				}
+				if len(vars) > 0 {
+					checkLoopBody(pass, body, vars)
+				}
+				return true
+			})
+		}
+	},
+}
+
+// sharesLoopVars reports whether f is compiled with the language version
+// before Go 1.22, in which all iterations of a loop share one variable. The
+// version comes from the module's go directive, or from a //go:build line in
+// the file. An unknown version is assumed to be current.
+func sharesLoopVars(pass *Pass, f *ast.File) bool {
+	v := pass.TypesInfo.FileVersions[f]
+	if v == "" {
+		v = pass.Pkg.GoVersion()
+	}
+	return version.IsValid(v) && version.Compare(v, "go1.22") < 0
+}
+
+func addLoopVar(info *types.Info, vars map[types.Object]bool, expr ast.Expr) {
+	if id, ok := expr.(*ast.Ident); ok && id.Name != "_" {
+		if obj := info.Defs[id]; obj != nil {
+			vars[obj] = true
+		}
+	}
+}
+
+// checkLoopBody reports the go statements in body whose function literal
+// refers to one of the loop's vars, or that take the address of one.
+func checkLoopBody(pass *Pass, body *ast.BlockStmt, vars map[types.Object]bool) {
+	ast.Inspect(body, func(n ast.Node) bool {
+		stmt, ok := n.(*ast.GoStmt)
+		if !ok {
+			return true
+		}
+		var captured []string
+		seen := map[types.Object]bool{}
+		capture := func(id *ast.Ident) {
+			if obj := pass.TypesInfo.Uses[id]; vars[obj] && !seen[obj] {
+				seen[obj] = true
+				captured = append(captured, id.Name)
+			}
+		}
+		if lit, ok := ast.Unparen(stmt.Call.Fun).(*ast.FuncLit); ok {
+			ast.Inspect(lit.Body, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok {
+					capture(id)
+				}
+				return true
+			})
+		}
+		// The address of the variable, taken explicitly or to call a method
+		// with a pointer receiver, is the same in every iteration too.
+		for _, arg := range stmt.Call.Args {
+			if addr, ok := ast.Unparen(arg).(*ast.UnaryExpr); ok && addr.Op == token.AND {
+				if id, ok := ast.Unparen(addr.X).(*ast.Ident); ok {
+					capture(id)
+				}
+			}
+		}
+		if sel, ok := ast.Unparen(stmt.Call.Fun).(*ast.SelectorExpr); ok {
+			if id, ok := ast.Unparen(sel.X).(*ast.Ident); ok && addressedReceiver(pass.TypesInfo, sel) {
+				capture(id)
+			}
+		}
+		if len(captured) == 0 {
+			return true
+		}
+		finding := pass.NewFinding(stmt, nil, "goroutine captures loop variable shared by all iterations")
+		finding.Node = strings.Join(captured, ", ")
+		finding.Fixes = append(finding.Fixes, shadowLoopVarsFix(pass, stmt, captured))
+		pass.ReportFinding(finding)
+		return true
+	})
+}
+
+// addressedReceiver reports whether the method selected by sel has a pointer
+// receiver while sel.X is not a pointer, so that calling it takes the
+// address of sel.X.
+func addressedReceiver(info *types.Info, sel *ast.SelectorExpr) bool {
+	selection, ok := info.Selections[sel]
+	if !ok || selection.Kind() != types.MethodVal {
+		return false
+	}
+	recv := selection.Obj().(*types.Func).Type().(*types.Signature).Recv()
+	_, ptrRecv := recv.Type().(*types.Pointer)
+	_, ptrX := selection.Recv().Underlying().(*types.Pointer)
+	return ptrRecv && !ptrX
+}
+
+// shadowLoopVarsFix declares a copy of each captured variable just before
+// stmt, giving each goroutine the value of its own iteration. That is what
+// the code almost certainly meant, and what Go 1.22 does anyway.
+func shadowLoopVarsFix(pass *Pass, stmt *ast.GoStmt, names []string) Fix {
+	var text strings.Builder
+	indent := lineIndent(pass.Pass, stmt.Pos())
+	for _, name := range names {
+		text.WriteString(name + " := " + name + "\n" + indent)
+	}
+	return Fix{
+		SuggestedFix: analysis.SuggestedFix{
+			Message:   "copy the loop variable for each goroutine",
+			TextEdits: []analysis.TextEdit{{Pos: stmt.Pos(), End: stmt.Pos(), NewText: []byte(text.String())}},
+		},
+		Safe: true,
+	}
+}
+
+// lineIndent returns the whitespace preceding pos on its line, or "" if the
+// source is unavailable.
+func lineIndent(pass *analysis.Pass, pos token.Pos) string {
+	position := pass.Fset.PositionFor(pos, false)
+	src, err := pass.ReadFile(position.Filename)
+	if err != nil || position.Offset > len(src) {
+		return ""
+	}
+	line := src[:position.Offset]
+	if i := bytes.LastIndexByte(line, '\n'); i >= 0 {
+		line = line[i+1:]
+	}
+	if len(bytes.TrimLeft(line, " \t")) > 0 {
+		return ""
+	}
+	return string(line)
+}
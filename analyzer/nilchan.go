@@ -0,0 +1,126 @@
+package analyzer
+
+import "go/ast"
+
+var nilChannel = &Check{
+	Name: "nil-channel",
+	ID:   "TS0027",
+	Doc:  "report sends, receives and closes on channels that are never made, or may not have been made yet",
+	Rationale: `The zero value of a channel is nil. Sending on or receiving from a nil
+channel blocks forever, and closing one panics. A channel variable or
+struct field that is declared but never assigned the result of make,
+including the fields of zero-value structs, is always nil; a local variable
+made only on some paths may still be nil where it is used. Operations in
+select cases are exempt, nil channels being the idiomatic way to disable
+a case.`,
+	Bad: `type Server struct {
+	quit chan struct{}
+}
+
+func (s *Server) Stop() {
+	close(s.quit) // s.quit is never made
+}`,
+	Good: `func NewServer() *Server {
+	return &Server{quit: make(chan struct{})}
+}`,
+	Run: func(pass *Pass) {
+		for _, ops := range pass.chanFlow {
+			if ops.foreign || ops.addressed {
+				continue
+			}
+			var decl *ast.ValueSpec
+			for _, n := range ops.nils {
+				if spec, ok := n.(*ast.ValueSpec); ok {
+					decl = spec
+				}
+			}
+			var operations []ast.Node
+			for _, send := range ops.sends {
+				operations = append(operations, send)
+			}
+			operations = append(operations, ops.recvs...)
+			for _, call := range ops.closes {
+				operations = append(operations, call)
+			}
+			for _, op := range operations {
+				path := enclosingPath(pass, op)
+				if inSelectCase(path) {
+					continue
+				}
+				ch, closed := operatedChan(op)
+				if ch == nil {
+					continue
+				}
+				switch {
+				case len(ops.makes) == 0 && closed:
+					pass.Reportf(ch, nil, "closing a channel that is never made panics")
+				case len(ops.makes) == 0:
+					pass.Reportf(ch, nil, "channel is never made, so this blocks forever")
+				case decl != nil && funcOf(enclosingPath(pass, decl)) == funcOf(path) && !madeBefore(pass, ops.makes, op):
+					pass.Reportf(ch, nil, "channel may not have been made yet here")
+				}
+			}
+		}
+	},
+}
+
+// operatedChan returns the channel operand of a send statement, receive
+// expression, range statement or close call, and whether it is a close.
+// Calls of functions closing a channel passed to them have no single
+// operand and yield nil.
+func operatedChan(op ast.Node) (ast.Expr, bool) {
+	switch op := op.(type) {
+	case *ast.SendStmt:
+		return op.Chan, false
+	case *ast.UnaryExpr:
+		return op.X, false
+	case *ast.RangeStmt:
+		return op.X, false
+	case *ast.CallExpr:
+		if fun, ok := ast.Unparen(op.Fun).(*ast.Ident); ok && fun.Name == "close" {
+			return op.Args[0], true
+		}
+	}
+	return nil, false
+}
+
+// inSelectCase reports whether the node at the head of path is the
+// communication of a select case.
+func inSelectCase(path []ast.Node) bool {
+	for _, n := range path {
+		switch n := n.(type) {
+		case *ast.CommClause:
+			return n.Comm != nil && n.Comm.Pos() <= path[0].Pos() && path[0].End() <= n.Comm.End()
+		case *ast.FuncLit, *ast.FuncDecl:
+			return false
+		}
+	}
+	return false
+}
+
+// madeBefore reports whether one of makes is assigned, in the function
+// containing op, before op and in a block enclosing it, so that it runs on
+// every path reaching op.
+func madeBefore(pass *Pass, makes []ast.Expr, op ast.Node) bool {
+	for _, m := range makes {
+		if m.Pos() >= op.Pos() {
+			continue
+		}
+		path := enclosingPath(pass, m)
+		if funcOf(path) != funcOf(enclosingPath(pass, op)) {
+			continue
+		}
+		for _, n := range path {
+			switch n.(type) {
+			case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+				if n.Pos() <= op.Pos() && op.End() <= n.End() {
+					return true
+				}
+			default:
+				continue
+			}
+			break
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+var useAfterTransfer = &Check{
+	Name: "use-after-transfer",
+	ID:   "TS0010",
+	Doc:  "report values used after their ownership was transferred by a //tsgo:owns or //tsgo:transfers annotation",
+	Rationale: `Sends on a channel annotated //tsgo:transfers, and arguments to parameters
+annotated //tsgo:owns, hand the value over to its new owner, so the pointer
+checks do not report them. That is only sound if the sender stops using the
+value: any later use races with the new owner. Assigning the variable a new
+value ends the transfer.`,
+	Bad: `jobs := make(chan *Job) //tsgo:transfers
+job := &Job{}
+jobs <- job
+job.Done = true // races with the receiver`,
+	Good: `jobs := make(chan *Job) //tsgo:transfers
+job := &Job{}
+job.Done = true
+jobs <- job`,
+	Run: func(pass *Pass) {
+		for _, f := range pass.Files {
+			for _, decl := range f.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				for _, directive := range []string{ownsDirective, transfersDirective} {
+					names, c := directiveNames(fd.Doc, directive)
+					for _, name := range names {
+						if !hasParamNamed(fd, name) {
+							pass.Reportf(c, nil, "%s names no parameter %s", directive[2:], name)
+						}
+					}
+				}
+			}
+		}
+		pass.Inspector.Preorder([]ast.Node{(*ast.SendStmt)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.SendStmt:
+				if pass.transfersOwnership(n.Chan) {
+					checkTransfer(pass, n.Value, n)
+				}
+			case *ast.CallExpr:
+				for i, arg := range n.Args {
+					if pass.ownedArg(n, i) {
+						checkTransfer(pass, arg, n)
+					}
+				}
+			}
+		})
+	},
+}
+
+func hasParamNamed(fd *ast.FuncDecl, name string) bool {
+	for _, field := range fd.Type.Params.List {
+		for _, id := range field.Names {
+			if id.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// transfersOwnership reports whether ch is a channel annotated with
+// //tsgo:transfers.
+func (p *Pass) transfersOwnership(ch ast.Expr) bool {
+	switch ch := ast.Unparen(ch).(type) {
+	case *ast.Ident:
+		return p.transferChans[p.TypesInfo.Uses[ch]]
+	case *ast.SelectorExpr:
+		if selection, ok := p.TypesInfo.Selections[ch]; ok {
+			return p.transferChans[selection.Obj()]
+		}
+		return p.transferChans[p.TypesInfo.Uses[ch.Sel]]
+	}
+	return false
+}
+
+// ownedArg reports whether the statically known callee of call takes
+// ownership of argument i.
+func (p *Pass) ownedArg(call *ast.CallExpr, i int) bool {
+	callee := typeutil.StaticCallee(p.TypesInfo, call)
+	if callee == nil {
+		return false
+	}
+	fact := p.facts.funcFact(callee)
+	return fact != nil && fact.paramOwned(i, callee.Type().(*types.Signature))
+}
+
+// checkTransfer reports the first use of the local variable value, if it is
+// one, after at hands it over. Inside a loop, uses earlier in an iteration
+// follow the transfer of the previous one, unless the variable is declared in
+// the loop itself.
+func checkTransfer(pass *Pass, value ast.Expr, at ast.Node) {
+	id, ok := ast.Unparen(value).(*ast.Ident)
+	if !ok {
+		return
+	}
+	v, ok := pass.TypesInfo.Uses[id].(*types.Var)
+	if !ok || v.Parent() == nil || v.Parent() == v.Pkg().Scope() {
+		return
+	}
+	body, loop := enclosingFunc(pass, at, v.Pos())
+	if body == nil {
+		return
+	}
+	reassigned := map[*ast.Ident]bool{}
+	var after, before *ast.Ident
+	ast.Inspect(body, func(n ast.Node) bool {
+		if assign, ok := n.(*ast.AssignStmt); ok && assign.Tok == token.ASSIGN {
+			for _, lhs := range assign.Lhs {
+				if lhs, ok := lhs.(*ast.Ident); ok {
+					reassigned[lhs] = true
+				}
+			}
+		}
+		use, ok := n.(*ast.Ident)
+		if !ok || pass.TypesInfo.Uses[use] != v {
+			return true
+		}
+		switch {
+		case use.Pos() >= at.End() && after == nil:
+			after = use
+		case loop != nil && use.Pos() >= loop.Pos() && use.Pos() < at.Pos() && before == nil:
+			before = use
+		}
+		return true
+	})
+	switch {
+	case after != nil && !reassigned[after]:
+		pass.Reportf(after, nil, "using value after transferring its ownership")
+	case after == nil && before != nil && !reassigned[before]:
+		pass.Reportf(before, nil, "using value after transferring its ownership in an earlier iteration")
+	}
+}
+
+// enclosingFunc returns the body of the innermost function declaration or
+// literal containing node and, if node is in a loop within it that begins
+// after declared, the innermost such loop, whose later iterations follow
+// node.
+func enclosingFunc(pass *Pass, node ast.Node, declared token.Pos) (*ast.BlockStmt, ast.Node) {
+	var loop ast.Node
+	for _, n := range enclosingPath(pass, node) {
+		switch n := n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt:
+			if loop == nil && declared < n.Pos() {
+				loop = n
+			}
+		case *ast.FuncLit:
+			return n.Body, loop
+		case *ast.FuncDecl:
+			return n.Body, loop
+		}
+	}
+	return nil, nil
+}
+
+// enclosingPath returns the nodes of the package's files enclosing node, from
+// node itself outward, as astutil.PathEnclosingInterval does.
+func enclosingPath(pass *Pass, node ast.Node) []ast.Node {
+	for _, f := range pass.Files {
+		if f.FileStart <= node.Pos() && node.Pos() < f.FileEnd {
+			path, _ := astutil.PathEnclosingInterval(f, node.Pos(), node.End())
+			return path
+		}
+	}
+	return nil
+}
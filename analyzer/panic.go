@@ -0,0 +1,174 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var goroutinePanic = &Check{
+	Name: "goroutine-panic",
+	ID:   "TS0035",
+	Doc:  "report goroutines that may panic without deferring a recover",
+	Rationale: `A panic that unwinds a goroutine's stack without being recovered crashes
+the whole process, whichever goroutine it started on; the main goroutine
+cannot recover it. A goroutine calling panic, a must-style helper such as
+regexp.MustCompile or template.Must, or asserting a type without checking
+the assertion should defer a function recovering the panic and reporting it
+as an error. Teams that let such panics crash the process on purpose can
+lower the check's severity or turn it off.`,
+	Bad: `go func() {
+	re := regexp.MustCompile(pattern)
+	results <- re.FindString(input)
+}()`,
+	Good: `go func() {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		errs <- err
+		return
+	}
+	results <- re.FindString(input)
+}()`,
+	Run: func(pass *Pass) {
+		// recovers holds the functions declared in the package that call
+		// recover, so that deferring them recovers panics.
+		recovers := map[*types.Func]bool{}
+		decls := map[*types.Func]*ast.FuncDecl{}
+		for _, f := range pass.Files {
+			for _, decl := range f.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Body == nil {
+					continue
+				}
+				if fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+					decls[fn] = fd
+					recovers[fn] = callsRecover(pass.TypesInfo, fd.Body)
+				}
+			}
+		}
+		checked := map[*ast.FuncDecl]bool{}
+		check := func(body *ast.BlockStmt) {
+			if defersRecover(pass.TypesInfo, body, recovers) {
+				return
+			}
+			if node, what := panicking(pass.TypesInfo, body); node != nil {
+				pass.Reportf(node, nil, "%s in a goroutine without a deferred recover crashes the process if it panics", what)
+			}
+		}
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil)}, func(n ast.Node) {
+			stmt := n.(*ast.GoStmt)
+			if lit, ok := ast.Unparen(stmt.Call.Fun).(*ast.FuncLit); ok {
+				check(lit.Body)
+				return
+			}
+			fn, ok := calledFunc(pass.TypesInfo, stmt.Call)
+			if !ok {
+				return
+			}
+			if isMustFunc(fn) {
+				reportGoStmt(pass, stmt, "goroutine calling a must-style helper crashes the process if it panics")
+			} else if fd := decls[fn]; fd != nil && !checked[fd] {
+				checked[fd] = true
+				check(fd.Body)
+			}
+		})
+	},
+}
+
+// panicking returns the first operation in body, outside function literals,
+// that panics or may panic, with a description of it, or nil if there is
+// none: a call of panic or of a must-style helper, or a type assertion
+// without a comma-ok check.
+func panicking(info *types.Info, body *ast.BlockStmt) (ast.Node, string) {
+	// checkedAsserts holds the type assertions whose failure is checked.
+	checkedAsserts := map[ast.Expr]bool{}
+	var node ast.Node
+	var what string
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			if len(n.Lhs) == 2 && len(n.Rhs) == 1 {
+				checkedAsserts[ast.Unparen(n.Rhs[0])] = true
+			}
+		case *ast.ValueSpec:
+			if len(n.Names) == 2 && len(n.Values) == 1 {
+				checkedAsserts[ast.Unparen(n.Values[0])] = true
+			}
+		case *ast.TypeSwitchStmt:
+			// The assertion in the switch guard cannot fail.
+			return true
+		case *ast.TypeAssertExpr:
+			if n.Type != nil && !checkedAsserts[n] {
+				node, what = n, "unchecked type assertion"
+			}
+		case *ast.CallExpr:
+			if id, ok := ast.Unparen(n.Fun).(*ast.Ident); ok && id.Name == "panic" {
+				if _, ok := info.Uses[id].(*types.Builtin); ok {
+					node, what = n, "panic"
+				}
+			} else if fn, ok := calledFunc(info, n); ok && isMustFunc(fn) {
+				node, what = n, "calling "+fn.Name()
+			}
+		}
+		return node == nil
+	})
+	return node, what
+}
+
+// isMustFunc reports whether fn is a must-style helper, named Must or
+// starting with Must or must followed by an upper-case letter, which panics
+// instead of returning an error.
+func isMustFunc(fn *types.Func) bool {
+	name := fn.Name()
+	if name == "Must" || name == "must" {
+		return true
+	}
+	rest, ok := strings.CutPrefix(name, "Must")
+	if !ok {
+		rest, ok = strings.CutPrefix(name, "must")
+	}
+	r, _ := utf8.DecodeRuneInString(rest)
+	return ok && unicode.IsUpper(r)
+}
+
+// defersRecover reports whether body defers, outside function literals, a
+// function literal calling recover or a function in recovers.
+func defersRecover(info *types.Info, body *ast.BlockStmt, recovers map[*types.Func]bool) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			if lit, ok := ast.Unparen(n.Call.Fun).(*ast.FuncLit); ok {
+				found = callsRecover(info, lit.Body)
+			} else if fn, ok := calledFunc(info, n.Call); ok {
+				found = recovers[fn]
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// callsRecover reports whether body calls recover outside function
+// literals, where it would recover nothing.
+func callsRecover(info *types.Info, body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			if id, ok := ast.Unparen(n.Fun).(*ast.Ident); ok && id.Name == "recover" {
+				_, found = info.Uses[id].(*types.Builtin)
+			}
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"go/types"
+	"reflect"
+	"strings"
+)
+
+// typeContainsPointer reports whether values of type t contain pointers, and
+// returns the type of the first pointer found. Types for which safe returns
+// true count as pointer-free without being inspected; safe may be nil. So do
+// struct fields tagged as deliberately shared; see sharedField.
+func typeContainsPointer(t types.Type, safe func(types.Type) bool) (bool, types.Type) {
+	if t != nil && safe != nil && safe(t) {
+		return false, nil
+	}
+	switch t := t.(type) {
+	case nil:
+		return false, nil
+	case *types.Alias:
+		return typeContainsPointer(types.Unalias(t), safe)
+	case *types.Array:
+		return typeContainsPointer(t.Elem(), safe)
+	case *types.Basic:
+		if t.Kind() == types.UnsafePointer {
+			return true, t
+		}
+		return false, nil
+	case *types.Chan:
+		return false, nil
+	case *types.Interface:
+		return false, nil
+	case *types.Map:
+		return true, t
+	case *types.Named:
+		return typeContainsPointer(t.Underlying(), safe)
+	case *types.Pointer:
+		return true, t
+	case *types.Slice:
+		return true, t
+	case *types.Struct:
+		numFields := t.NumFields()
+		for i := 0; i < numFields; i++ {
+			if sharedField(t.Tag(i)) {
+				continue
+			}
+			fieldType := t.Field(i).Type()
+			if contains, subType := typeContainsPointer(fieldType, safe); contains {
+				return true, subType
+			}
+		}
+		return false, nil
+	case *types.TypeParam:
+		if iface, ok := t.Constraint().Underlying().(*types.Interface); ok && !typeSetContainsPointer(iface, safe) {
+			return false, nil
+		}
+		return true, t
+	case *types.Union:
+		for i := 0; i < t.Len(); i++ {
+			if contains, subType := typeContainsPointer(t.Term(i).Type(), safe); contains {
+				return true, subType
+			}
+		}
+		return false, nil
+		// case *types.Nil:
+		// 	return false
+		// case *types.Object:
+		// 	return true
+	}
+	return true, t
+}
+
+// sharedField reports whether a struct field with the given tag is declared
+// safe to share, with a tsgo:"shared" or tsgo:"immutable" tag:
+//
+//	type Request struct {
+//		Config *Config `tsgo:"immutable"`
+//		Body   []byte
+//	}
+//
+// The field is then not inspected for pointers; the rest of the struct
+// still is.
+func sharedField(tag string) bool {
+	value, ok := reflect.StructTag(tag).Lookup("tsgo")
+	if !ok {
+		return false
+	}
+	for _, option := range strings.Split(value, ",") {
+		if option == "shared" || option == "immutable" {
+			return true
+		}
+	}
+	return false
+}
+
+// isNested reports whether pointer, as returned by typeContainsPointer for
+// payload, lies inside a struct or array rather than being the payload
+// itself.
+func isNested(payload, pointer types.Type) bool {
+	switch payload.Underlying().(type) {
+	case *types.Struct, *types.Array:
+		return !types.Identical(payload, pointer)
+	}
+	return false
+}
+
+// typeSetContainsPointer reports whether any type in the type set of a
+// constraint interface may contain pointers. The type set is the intersection
+// of the embedded elements, so a single pointer-free element suffices.
+func typeSetContainsPointer(iface *types.Interface, safe func(types.Type) bool) bool {
+	if iface.IsMethodSet() {
+		return true
+	}
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		embedded := iface.EmbeddedType(i)
+		if inner, ok := embedded.Underlying().(*types.Interface); ok {
+			if !typeSetContainsPointer(inner, safe) {
+				return false
+			}
+		} else if contains, _ := typeContainsPointer(embedded, safe); !contains {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package analyzer
+
+import "sort"
+
+// A Preset is a named starting point for the check selection, which drivers
+// then adjust with their own configuration.
+type Preset struct {
+	// Include lists checks that are off by default to run anyway, and
+	// Disable checks that are on by default not to run.
+	Include, Disable []string
+	// NestedSeverity, if set, is the severity of findings about pointers
+	// nested in struct or array payloads, for checks not given a severity
+	// explicitly.
+	NestedSeverity Severity
+}
+
+var presets = map[string]Preset{
+	"default": {},
+	// strict also reports interface payloads, which may hide pointers, and
+	// variables shared read-only, which a single write would make race.
+	"strict": {Include: []string{"interface-payload", "read-only-sharing"}},
+	// relaxed leaves package-level variables alone and reports pointers
+	// inside struct payloads, often deliberate handles, as info only.
+	"relaxed": {Disable: []string{"global-var"}, NestedSeverity: SeverityInfo},
+}
+
+// LookupPreset returns the preset called name, if there is one.
+func LookupPreset(name string) (Preset, bool) {
+	p, ok := presets[name]
+	return p, ok
+}
+
+// PresetNames returns the names of the presets, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,68 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+var discardedGoResult = &Check{
+	Name: "discarded-go-result",
+	ID:   "TS0040",
+	Doc:  "report go statements and spawn wrappers starting functions whose results are discarded",
+	Rationale: `The results of a function started by a go statement are thrown away. When
+one of them is an error, the failure goes unnoticed on a goroutine nobody
+hears from. Run the function with errgroup.Group.Go, or send its results
+to the spawner over a channel. A call site discarding the results on
+purpose can say so with _ = f() in a function literal, or a
+//tsgo:ignore directive.`,
+	Bad: `go sync(ctx, dst, src) // the error sync returns is lost`,
+	Good: `g, ctx := errgroup.WithContext(ctx)
+g.Go(func() error {
+	return sync(ctx, dst, src)
+})
+if err := g.Wait(); err != nil {
+	return err
+}`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.GoStmt:
+				if sig, ok := types.Unalias(pass.TypesInfo.TypeOf(n.Call.Fun)).Underlying().(*types.Signature); ok && sig.Results().Len() > 0 {
+					reportGoStmt(pass, n, "goroutine discards the "+resultsOf(sig)+" its function returns")
+				}
+			case *ast.CallExpr:
+				callee := typeutil.StaticCallee(pass.TypesInfo, n)
+				if callee == nil || !pass.spawnWrappers[callee.Origin().FullName()] {
+					return
+				}
+				params := callee.Type().(*types.Signature).Params()
+				for i, arg := range n.Args {
+					sig, ok := types.Unalias(pass.TypesInfo.TypeOf(arg)).Underlying().(*types.Signature)
+					if !ok || sig.Results().Len() == 0 || i >= params.Len() {
+						continue
+					}
+					// A wrapper taking functions with results handles them,
+					// as errgroup.Group.Go does.
+					if param, ok := params.At(i).Type().Underlying().(*types.Signature); ok && param.Results().Len() > 0 {
+						continue
+					}
+					pass.Reportf(arg, nil, "%s discards the %s its function returns", callee.Name(), resultsOf(sig))
+				}
+			}
+		})
+	},
+}
+
+// resultsOf describes the results of sig: "error" when it returns only an
+// error, and "results" or "result" otherwise.
+func resultsOf(sig *types.Signature) string {
+	switch {
+	case sig.Results().Len() == 1 && isError(sig.Results().At(0).Type()):
+		return "error"
+	case sig.Results().Len() == 1:
+		return "result"
+	}
+	return "results"
+}
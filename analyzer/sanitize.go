@@ -0,0 +1,191 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// sanitizerKind classifies how a call to a configured sanitizer launders
+// values.
+type sanitizerKind int
+
+const (
+	notSanitizer sanitizerKind = iota
+	// returnsCopy sanitizers return a fresh copy, as proto.Clone does.
+	returnsCopy
+	// fillsFirst sanitizers copy into the value their first argument points
+	// to, as copier.Copy does, and return at most an error.
+	fillsFirst
+)
+
+// sanitizerCall returns how call launders values, given the sanitizers named
+// by their types.Func.FullName, such as
+// "google.golang.org/protobuf/proto.Clone" or "(*example.com/pb.Msg).Copy".
+func sanitizerCall(info *types.Info, sanitizers map[string]bool, call *ast.CallExpr) sanitizerKind {
+	if len(sanitizers) == 0 {
+		return notSanitizer
+	}
+	callee := typeutil.StaticCallee(info, call)
+	if callee == nil || !sanitizers[callee.Origin().FullName()] {
+		return notSanitizer
+	}
+	results := callee.Type().(*types.Signature).Results()
+	if results.Len() == 0 || results.Len() == 1 && isError(results.At(0).Type()) {
+		return fillsFirst
+	}
+	return returnsCopy
+}
+
+// collectFreshVars returns the local variables of files only ever assigned
+// the result of a sanitizer or clone method, or filled by a sanitizer, so that sending them shares
+// nothing with the code that produced the original.
+func collectFreshVars(pass *analysis.Pass, files []*ast.File, sanitizers map[string]bool) map[*types.Var]bool {
+	fresh := map[*types.Var]bool{}
+	tainted := map[*types.Var]bool{}
+	local := func(id *ast.Ident) *types.Var {
+		obj := pass.TypesInfo.Defs[id]
+		if obj == nil {
+			obj = pass.TypesInfo.Uses[id]
+		}
+		if v, ok := obj.(*types.Var); ok && !v.IsField() && v.Parent() != v.Pkg().Scope() {
+			return v
+		}
+		return nil
+	}
+	assign := func(lhs []ast.Expr, rhs []ast.Expr) {
+		for i, expr := range lhs {
+			id, ok := ast.Unparen(expr).(*ast.Ident)
+			if !ok {
+				continue
+			}
+			v := local(id)
+			if v == nil {
+				continue
+			}
+			if len(rhs) == len(lhs) && isSanitized(pass.TypesInfo, sanitizers, rhs[i]) {
+				fresh[v] = true
+			} else {
+				tainted[v] = true
+			}
+		}
+	}
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				assign(n.Lhs, n.Rhs)
+			case *ast.ValueSpec:
+				if len(n.Values) > 0 {
+					lhs := make([]ast.Expr, len(n.Names))
+					for i, id := range n.Names {
+						lhs[i] = id
+					}
+					assign(lhs, n.Values)
+				}
+			case *ast.RangeStmt:
+				for _, expr := range []ast.Expr{n.Key, n.Value} {
+					if id, ok := expr.(*ast.Ident); ok {
+						if v := local(id); v != nil {
+							tainted[v] = true
+						}
+					}
+				}
+			case *ast.CallExpr:
+				if len(n.Args) == 0 || sanitizerCall(pass.TypesInfo, sanitizers, n) != fillsFirst {
+					break
+				}
+				if addr, ok := ast.Unparen(n.Args[0]).(*ast.UnaryExpr); ok {
+					if id, ok := ast.Unparen(addr.X).(*ast.Ident); ok {
+						if v := local(id); v != nil {
+							fresh[v] = true
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	for v := range tainted {
+		delete(fresh, v)
+	}
+	return fresh
+}
+
+// isSanitized reports whether expr is the result of a sanitizer returning a
+// copy or of a clone method, possibly type-asserted, as in
+// proto.Clone(msg).(*pb.Msg).
+func isSanitized(info *types.Info, sanitizers map[string]bool, expr ast.Expr) bool {
+	for {
+		switch e := ast.Unparen(expr).(type) {
+		case *ast.TypeAssertExpr:
+			expr = e.X
+		case *ast.CallExpr:
+			return sanitizerCall(info, sanitizers, e) == returnsCopy || isCloneCall(info, e)
+		default:
+			return false
+		}
+	}
+}
+
+// cloneMethodNames are the methods recognized as returning a deep copy of
+// their receiver when they take no arguments and return the receiver's type.
+var cloneMethodNames = []string{"Clone", "DeepCopy"}
+
+// cloneMethod returns the clone method of t, or nil if it has none.
+func cloneMethod(t types.Type) *types.Func {
+	if t == nil {
+		return nil
+	}
+	for _, name := range cloneMethodNames {
+		obj, _, _ := types.LookupFieldOrMethod(t, true, nil, name)
+		fn, ok := obj.(*types.Func)
+		if !ok {
+			continue
+		}
+		sig := fn.Type().(*types.Signature)
+		if sig.Params().Len() == 0 && sig.Results().Len() == 1 && types.Identical(sig.Results().At(0).Type(), t) {
+			return fn
+		}
+	}
+	return nil
+}
+
+// isCloneCall reports whether call invokes the clone method of its receiver.
+func isCloneCall(info *types.Info, call *ast.CallExpr) bool {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || len(call.Args) > 0 {
+		return false
+	}
+	fn := cloneMethod(info.TypeOf(sel.X))
+	return fn != nil && fn.Name() == sel.Sel.Name
+}
+
+// cloneHint returns advice to append to the message of a finding about a
+// payload of type t, naming its clone method if it has one.
+func cloneHint(t types.Type) string {
+	if fn := cloneMethod(t); fn != nil {
+		return "; copy it with " + fn.Name() + " first"
+	}
+	return ""
+}
+
+// isFresh reports whether payload is known to share no memory with the
+// sender's other values, being the result of a configured sanitizer or clone
+// method, or a variable holding one.
+func (p *Pass) isFresh(payload ast.Expr) bool {
+	if isSanitized(p.TypesInfo, p.sanitizers, payload) {
+		return true
+	}
+	if addr, ok := ast.Unparen(payload).(*ast.UnaryExpr); ok && addr.Op == token.AND {
+		payload = addr.X
+	}
+	if id, ok := ast.Unparen(payload).(*ast.Ident); ok {
+		v, _ := p.TypesInfo.Uses[id].(*types.Var)
+		return p.freshVars[v]
+	}
+	return false
+}
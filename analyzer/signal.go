@@ -0,0 +1,69 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/constant"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+var unbufferedSignal = &Check{
+	Name: "unbuffered-signal",
+	ID:   "TS0039",
+	Doc:  "report channels passed to signal.Notify that are made without a buffer",
+	Rationale: `signal.Notify never blocks sending a signal: when the channel has no room,
+the signal is dropped. An unbuffered channel only has room while a receiver
+is waiting on it, so a signal arriving before the program starts receiving,
+or while it handles the previous one, is lost. A buffer of one is enough for
+a single kind of signal.`,
+	Bad: `sigs := make(chan os.Signal)
+signal.Notify(sigs, os.Interrupt)`,
+	Good: `sigs := make(chan os.Signal, 1)
+signal.Notify(sigs, os.Interrupt)`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+			call := n.(*ast.CallExpr)
+			callee := typeutil.StaticCallee(pass.TypesInfo, call)
+			if callee == nil || callee.FullName() != "os/signal.Notify" || len(call.Args) == 0 {
+				return
+			}
+			ch := call.Args[0]
+			makes := []ast.Expr{ch}
+			if !isMakeCall(pass.TypesInfo, ch) {
+				ops := pass.chanFlow.of(pass.TypesInfo, ch)
+				if ops == nil || ops.foreign || ops.addressed || len(ops.makes) == 0 || len(ops.nils) > 0 {
+					return
+				}
+				makes = ops.makes
+			}
+			var edits []analysis.TextEdit
+			for _, expr := range makes {
+				make := ast.Unparen(expr).(*ast.CallExpr)
+				switch {
+				case len(make.Args) == 1:
+					edits = append(edits, analysis.TextEdit{Pos: make.Args[0].End(), End: make.Args[0].End(), NewText: []byte(", 1")})
+				case isZero(pass, make.Args[1]):
+					edits = append(edits, analysis.TextEdit{Pos: make.Args[1].Pos(), End: make.Args[1].End(), NewText: []byte("1")})
+				default:
+					return
+				}
+			}
+			finding := pass.NewFinding(ch, nil, "signal.Notify drops signals sent while nothing receives from an unbuffered channel; make it with capacity 1")
+			finding.Fixes = append(finding.Fixes, Fix{
+				SuggestedFix: analysis.SuggestedFix{
+					Message:   "make the channel with capacity 1",
+					TextEdits: edits,
+				},
+				Safe: true,
+			})
+			pass.ReportFinding(finding)
+		})
+	},
+}
+
+// isZero reports whether expr is a constant equal to zero.
+func isZero(pass *Pass, expr ast.Expr) bool {
+	value := pass.TypesInfo.Types[expr].Value
+	return value != nil && constant.Sign(value) == 0
+}
@@ -0,0 +1,174 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// spawnWrappers lists the functions and methods, by the full name
+// types.Func.FullName gives them, that start a goroutine running their
+// function argument, as a go statement does. Config.SpawnWrappers adds to
+// the list.
+var spawnWrappers = []string{
+	"(*golang.org/x/sync/errgroup.Group).Go",
+	"(*github.com/sourcegraph/conc.WaitGroup).Go",
+	"(*github.com/sourcegraph/conc/pool.Pool).Go",
+}
+
+// limitMethods are the methods recognized as bounding the goroutines a
+// group or pool starts, such as errgroup.Group.SetLimit.
+var limitMethods = map[string]bool{
+	"SetLimit":          true,
+	"WithMaxGoroutines": true,
+	"Acquire":           true,
+}
+
+var unboundedSpawn = &Check{
+	Name: "unbounded-spawn",
+	ID:   "TS0021",
+	Doc:  "report goroutines started in loops over open-ended input with nothing limiting how many run at once",
+	Rationale: `Starting a goroutine per value received from a channel or an iterator, or
+per accepted connection or line read, creates as many goroutines as there is
+input, with no end in sight. Under load they exhaust memory, file
+descriptors or the capacity of whatever they call. Only such open-ended
+loops are reported: loops over slices, arrays, maps and strings are bounded
+by input already in memory, and loops counting up to a worker count by the
+count. Loops acquiring a semaphore, by sending on a channel or calling
+Acquire, before starting the goroutine are taken as bounded, as are
+functions calling SetLimit on an errgroup.`,
+	Bad: `for conn := range conns {
+	go serve(conn)
+}`,
+	Good: `sem := make(chan struct{}, 8)
+for conn := range conns {
+	sem <- struct{}{}
+	go func() {
+		defer func() { <-sem }()
+		serve(conn)
+	}()
+}`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node) {
+			if call, ok := n.(*ast.CallExpr); ok {
+				callee := typeutil.StaticCallee(pass.TypesInfo, call)
+				if callee == nil || !pass.spawnWrappers[callee.Origin().FullName()] {
+					return
+				}
+			}
+			loop, body := unboundedLoop(pass, n)
+			if loop == nil || limitsSpawns(pass, body, loop, n) {
+				return
+			}
+			finding := pass.NewFinding(n, nil, "goroutine started in a loop over open-ended input without a limit")
+			finding.Node = loopHeader(pass, loop)
+			pass.ReportFinding(finding)
+		})
+	},
+}
+
+// unboundedLoop returns the outermost loop around spawn, within the function
+// containing it, that runs for as long as its input lasts: a range over a
+// channel or an iterator function, or a for loop that does not count up to a
+// fixed number of iterations. It returns the body of that function along with
+// it, or nil if there is no such loop.
+func unboundedLoop(pass *Pass, spawn ast.Node) (ast.Node, *ast.BlockStmt) {
+	var loop ast.Node
+	for _, n := range enclosingPath(pass, spawn) {
+		switch n := n.(type) {
+		case *ast.ForStmt:
+			if n.Init == nil || n.Cond == nil || n.Post == nil {
+				loop = n
+			}
+		case *ast.RangeStmt:
+			if t := pass.TypesInfo.TypeOf(n.X); t != nil {
+				switch t.Underlying().(type) {
+				case *types.Chan, *types.Signature:
+					loop = n
+				}
+			}
+		case *ast.FuncLit:
+			return loop, n.Body
+		case *ast.FuncDecl:
+			return loop, n.Body
+		}
+	}
+	return nil, nil
+}
+
+// loopHeader returns a short description of loop for findings, such as
+// "range urls" or "for conn != nil".
+func loopHeader(pass *Pass, loop ast.Node) string {
+	switch loop := loop.(type) {
+	case *ast.RangeStmt:
+		return "range " + stringifyNode(pass.Fset, loop.X)
+	case *ast.ForStmt:
+		if loop.Cond != nil {
+			return "for " + stringifyNode(pass.Fset, loop.Cond)
+		}
+	}
+	return "for"
+}
+
+// limitsSpawns reports whether body limits the goroutines spawn starts in
+// loop: by calling a limit method anywhere, or by sending on a channel or
+// acquiring a semaphore in loop before spawn, outside any function literal.
+func limitsSpawns(pass *Pass, body *ast.BlockStmt, loop, spawn ast.Node) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// A limit set up in a function literal may never run, and one
+			// inside the goroutine does not stop it starting.
+			return false
+		case *ast.SendStmt:
+			found = found || n.Pos() >= loop.Pos() && n.End() <= spawn.Pos()
+		case *ast.CallExpr:
+			if sel, ok := ast.Unparen(n.Fun).(*ast.SelectorExpr); ok && limitMethods[sel.Sel.Name] {
+				found = found || sel.Sel.Name != "Acquire" || n.Pos() >= loop.Pos() && n.End() <= spawn.Pos()
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+var initGoroutine = &Check{
+	Name: "init-goroutine",
+	ID:   "TS0033",
+	Doc:  "report goroutines started in init functions",
+	Rationale: `A goroutine started by an init function runs before main begins, while
+other packages are still initializing. It may see package-level variables
+before they are set, and it runs in every program and test linking the
+package, whether or not they use it. Start background work from an explicit
+function the program calls instead.`,
+	Bad: `func init() {
+	go refreshCache()
+}`,
+	Good: `// Start begins refreshing the cache in the background.
+func Start() {
+	go refreshCache()
+}`,
+	Run: func(pass *Pass) {
+		for _, f := range pass.Files {
+			for _, decl := range f.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Recv != nil || fd.Name.Name != "init" || fd.Body == nil {
+					continue
+				}
+				ast.Inspect(fd.Body, func(n ast.Node) bool {
+					switch n := n.(type) {
+					case *ast.GoStmt:
+						reportGoStmt(pass, n, "goroutine started in an init function")
+					case *ast.CallExpr:
+						if callee := typeutil.StaticCallee(pass.TypesInfo, n); callee != nil && pass.spawnWrappers[callee.Origin().FullName()] {
+							pass.Reportf(n.Fun, nil, "goroutine started in an init function")
+						}
+					}
+					return true
+				})
+			}
+		}
+	},
+}
@@ -0,0 +1,230 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"math"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// ignoreDirective is the comment prefix that suppresses findings:
+//
+//	//tsgo:ignore chan-pointer-send -- the receiver takes ownership
+//
+// on the offending line, or on a line of its own just above it, suppresses
+// findings of the named checks, given by name or ID and separated by commas.
+// Without a check name, every check is suppressed at that line. The reason
+// after "--" is for readers and is required in strict mode, like the check.
+//
+// In the doc comment of a function, the directive covers the whole function.
+// ignoreFileDirective, written the same way before the package clause,
+// covers the whole file.
+const (
+	ignoreDirective     = "//tsgo:ignore"
+	ignoreFileDirective = "//tsgo:ignorefile"
+)
+
+// A suppression is a parsed ignore directive and the lines it covers.
+type suppression struct {
+	pos      token.Pos
+	filename string
+	from, to int
+	checks   []string
+	reason   string
+	// unknown lists the names in checks that match no registered check.
+	unknown []string
+	text    string
+	// used is set once the suppression has suppressed a finding.
+	used bool
+}
+
+// covers reports whether s suppresses finding.
+func (s *suppression) covers(fset *token.FileSet, finding *Finding) bool {
+	position := fset.Position(finding.Pos)
+	if position.Filename != s.filename || position.Line < s.from || position.Line > s.to {
+		return false
+	}
+	if len(s.checks) == 0 {
+		return true
+	}
+	for _, name := range s.checks {
+		if name == finding.Check.Name || name == finding.Check.ID && name != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSuppression parses the text of a comment, returning ok if it is the
+// given directive.
+func parseSuppression(text, directive string) (checks []string, reason string, ok bool) {
+	rest, ok := strings.CutPrefix(text, directive)
+	if !ok || rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return nil, "", false
+	}
+	spec, reason, _ := strings.Cut(rest, "--")
+	for _, name := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		checks = append(checks, name)
+	}
+	return checks, strings.TrimSpace(reason), true
+}
+
+// collectSuppressions returns the ignore directives in files.
+func collectSuppressions(pass *analysis.Pass, files []*ast.File) []*suppression {
+	var suppressions []*suppression
+	for _, f := range files {
+		// The last line of the function each doc comment belongs to.
+		funcEnds := map[*ast.CommentGroup]int{}
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Doc != nil {
+				funcEnds[fd.Doc] = pass.Fset.Position(fd.End()).Line
+			}
+		}
+		for _, group := range f.Comments {
+			for _, c := range group.List {
+				directive := ignoreDirective
+				checks, reason, ok := parseSuppression(c.Text, directive)
+				if !ok && c.Pos() < f.Package {
+					directive = ignoreFileDirective
+					checks, reason, ok = parseSuppression(c.Text, directive)
+				}
+				if !ok {
+					continue
+				}
+				position := pass.Fset.Position(c.Pos())
+				s := &suppression{
+					pos:      c.Pos(),
+					filename: position.Filename,
+					from:     position.Line,
+					to:       position.Line,
+					checks:   checks,
+					reason:   reason,
+					text:     c.Text,
+				}
+				switch {
+				case directive == ignoreFileDirective:
+					s.from, s.to = 1, math.MaxInt
+				case funcEnds[group] > 0:
+					s.to = funcEnds[group]
+				case startsLine(pass, c.Pos()):
+					s.to++
+				}
+				for _, name := range checks {
+					if Lookup(name) == nil {
+						s.unknown = append(s.unknown, name)
+					}
+				}
+				suppressions = append(suppressions, s)
+			}
+		}
+	}
+	return suppressions
+}
+
+// startsLine reports whether nothing but whitespace precedes pos on its line.
+func startsLine(pass *analysis.Pass, pos token.Pos) bool {
+	position := pass.Fset.PositionFor(pos, false)
+	if position.Column == 1 {
+		return true
+	}
+	return lineIndent(pass, pos) != ""
+}
+
+// suppressed reports whether a directive covers finding. Findings about the
+// directives themselves cannot be suppressed.
+func (p *Pass) suppressed(finding *Finding) bool {
+	if finding.Check.Name == suppressionCheckName {
+		return false
+	}
+	for _, s := range p.suppressions {
+		if s.covers(p.Fset, finding) && p.effective(s) {
+			s.used = true
+			return true
+		}
+	}
+	return false
+}
+
+// effective reports whether s takes effect: in strict mode, only directives
+// naming a check and giving a reason do.
+func (p *Pass) effective(s *suppression) bool {
+	return !p.strictSuppressions || len(s.checks) > 0 && s.reason != ""
+}
+
+// reportUnusedSuppressions reports the effective directives that suppressed
+// nothing, once every selected check has run. Directives naming a check that
+// did not run are left alone, since they may well be needed when it does, and
+// so are blanket directives unless every registered check ran.
+func reportUnusedSuppressions(pass *Pass, selected []*Check) {
+	ran := map[string]bool{}
+	for _, check := range selected {
+		ran[check.Name] = true
+		if check.ID != "" {
+			ran[check.ID] = true
+		}
+	}
+	for _, s := range pass.suppressions {
+		if s.used || len(s.unknown) > 0 || !pass.effective(s) {
+			continue
+		}
+		names := s.checks
+		if len(names) == 0 {
+			for _, check := range Checks() {
+				names = append(names, check.Name)
+			}
+		}
+		all := true
+		for _, name := range names {
+			all = all && ran[name]
+		}
+		if all {
+			pass.ReportFinding(&Finding{
+				Check:   pass.Check,
+				Pos:     s.pos,
+				End:     s.pos + token.Pos(len(s.text)),
+				Message: "suppression does not suppress any finding",
+				Node:    s.text,
+			})
+		}
+	}
+}
+
+const suppressionCheckName = "suppression"
+
+var suppressionCheck = &Check{
+	Name: suppressionCheckName,
+	ID:   "TS0007",
+	Doc:  "report //tsgo:ignore directives that suppress nothing or name unknown checks, or in strict mode lack a check or reason",
+	Rationale: `A suppression naming a check that does not exist silences nothing, usually
+because of a typo or a renamed check. One that no longer suppresses any
+finding, because the code was fixed, would silently hide the next problem
+introduced at that spot. In strict mode, every suppression must also say
+which check it silences and why, so that blanket or unexplained
+suppressions do not hide real problems; such directives are ignored.`,
+	Bad:  `ch <- p //tsgo:ignore`,
+	Good: `ch <- p //tsgo:ignore chan-pointer-send -- the receiver takes ownership`,
+	Run: func(pass *Pass) {
+		for _, s := range pass.suppressions {
+			for _, name := range s.unknown {
+				pass.ReportFinding(&Finding{
+					Check:   pass.Check,
+					Pos:     s.pos,
+					End:     s.pos,
+					Message: "suppression names unknown check",
+					Node:    name,
+				})
+			}
+			if !pass.strictSuppressions {
+				continue
+			}
+			if len(s.checks) == 0 {
+				pass.ReportFinding(&Finding{Check: pass.Check, Pos: s.pos, End: s.pos, Message: "suppression does not name a check", Node: ignoreDirective})
+			}
+			if s.reason == "" {
+				pass.ReportFinding(&Finding{Check: pass.Check, Pos: s.pos, End: s.pos, Message: "suppression does not give a reason", Node: ignoreDirective})
+			}
+		}
+	},
+}
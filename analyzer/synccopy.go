@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+var syncValueSend = &Check{
+	Name: "sync-value-send",
+	ID:   "TS0020",
+	Doc:  "report values containing sync primitives, such as sync.Mutex or sync.WaitGroup, sent over channels",
+	Rationale: `Sending a value over a channel copies it. A sync.Mutex, sync.WaitGroup,
+sync.Once or sync/atomic value copied this way carries its current state to
+the receiver, which then locks, waits on or updates a copy the sender never
+sees: the synchronization silently stops working. go vet's copylocks check
+reports copies in assignments and calls but not in channel sends.`,
+	Bad: `type counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+results <- c // results is a chan counter`,
+	Good: `results <- &c // results is a chan *counter, sharing one mutex`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.SendStmt)(nil)}, func(n ast.Node) {
+			send := n.(*ast.SendStmt)
+			if t := findType(pass.TypesInfo.TypeOf(send.Value), isSyncPrimitive); t != nil {
+				pass.Reportf(send.Value, t, "sending value containing a sync primitive over a channel copies it")
+			}
+		})
+	},
+}
+
+// isSyncPrimitive reports whether t is a type of sync or sync/atomic, or any
+// other type that must not be copied after first use, recognized as go vet
+// does by a Lock and Unlock method on its pointer but not on itself.
+func isSyncPrimitive(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return false
+	}
+	if pkg := named.Obj().Pkg(); pkg != nil && (pkg.Path() == "sync" || pkg.Path() == "sync/atomic") {
+		if _, ok := named.Underlying().(*types.Struct); ok {
+			return true
+		}
+	}
+	return isLock(named)
+}
+
+// isLock reports whether t is a lock that must not be copied after first
+// use, such as sync.Mutex: a named type with Lock and Unlock methods on its
+// pointer but not on itself, the convention go vet's copylocks follows. The
+// unexported noCopy markers of sync and sync/atomic are not locks
+// themselves; the exported types holding them, such as sync.WaitGroup, are.
+func isLock(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return false
+	}
+	if pkg := named.Obj().Pkg(); pkg != nil && (pkg.Path() == "sync" || pkg.Path() == "sync/atomic") {
+		if !named.Obj().Exported() {
+			return false
+		}
+		if st, ok := named.Underlying().(*types.Struct); ok {
+			for i := 0; i < st.NumFields(); i++ {
+				if field, ok := types.Unalias(st.Field(i).Type()).(*types.Named); ok && lockShaped(field) {
+					return true
+				}
+			}
+		}
+	}
+	return lockShaped(named)
+}
+
+// lockShaped reports whether named has Lock and Unlock methods on its
+// pointer but not on itself.
+func lockShaped(named *types.Named) bool {
+	hasLocker := func(t types.Type) bool {
+		lock, _, _ := types.LookupFieldOrMethod(t, false, named.Obj().Pkg(), "Lock")
+		unlock, _, _ := types.LookupFieldOrMethod(t, false, named.Obj().Pkg(), "Unlock")
+		_, isLock := lock.(*types.Func)
+		_, isUnlock := unlock.(*types.Func)
+		return isLock && isUnlock
+	}
+	return !hasLocker(named) && hasLocker(types.NewPointer(named))
+}
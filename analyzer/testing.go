@@ -0,0 +1,354 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// failNowMethods are the methods of testing.T, testing.B, testing.F and
+// testing.TB that end the test by calling runtime.Goexit, which only works
+// on the test's own goroutine.
+var failNowMethods = map[string]bool{
+	"FailNow": true,
+	"Fatal":   true,
+	"Fatalf":  true,
+	"SkipNow": true,
+	"Skip":    true,
+	"Skipf":   true,
+}
+
+var testGoroutineFatal = &Check{
+	Name: "test-goroutine-fatal",
+	ID:   "TS0036",
+	Doc:  "report t.Fatal, t.FailNow and t.Skip called from goroutines started by a test",
+	Rationale: `FailNow, and Fatal and Skip which call it, stop a test by exiting the
+goroutine they are called from. Called from a goroutine the test started,
+they exit that goroutine instead: the test carries on as if nothing
+happened, and may finish before the failure is even recorded. Call Error
+and return instead, or send the error to the test's goroutine.`,
+	Bad: `go func() {
+	if err := serve(); err != nil {
+		t.Fatal(err)
+	}
+}()`,
+	Good: `errs := make(chan error, 1)
+go func() {
+	errs <- serve()
+}()
+if err := <-errs; err != nil {
+	t.Fatal(err)
+}`,
+	Run: func(pass *Pass) {
+		decls := map[*types.Func]*ast.FuncDecl{}
+		var testFiles []*ast.File
+		for _, f := range pass.Files {
+			if !strings.HasSuffix(pass.Fset.Position(f.Package).Filename, "_test.go") {
+				continue
+			}
+			testFiles = append(testFiles, f)
+			for _, decl := range f.Decls {
+				if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil {
+					if fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+						decls[fn] = fd
+					}
+				}
+			}
+		}
+		checked := map[*ast.FuncDecl]bool{}
+		check := func(body *ast.BlockStmt) {
+			ast.Inspect(body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncLit:
+					// Function literals may run anywhere, as subtests do.
+					return false
+				case *ast.CallExpr:
+					if fn, ok := calledFunc(pass.TypesInfo, n); ok && isFailNow(fn) {
+						pass.Reportf(n, nil, "%s called from a goroutine started by the test exits that goroutine, not the test", fn.Name())
+					}
+				}
+				return true
+			})
+		}
+		for _, f := range testFiles {
+			ast.Inspect(f, func(n ast.Node) bool {
+				stmt, ok := n.(*ast.GoStmt)
+				if !ok {
+					return true
+				}
+				if lit, ok := ast.Unparen(stmt.Call.Fun).(*ast.FuncLit); ok {
+					check(lit.Body)
+				} else if fn, ok := calledFunc(pass.TypesInfo, stmt.Call); ok && isFailNow(fn) {
+					reportGoStmt(pass, stmt, "goroutine calling "+fn.Name()+" exits itself, not the test")
+				} else if fd := decls[fn]; ok && fd != nil && !checked[fd] {
+					checked[fd] = true
+					check(fd.Body)
+				}
+				return true
+			})
+		}
+	},
+}
+
+// isFailNow reports whether fn is a method of the testing package stopping
+// the test it is called on.
+func isFailNow(fn *types.Func) bool {
+	return failNowMethods[fn.Name()] && isTestingMethod(fn, fn.Name())
+}
+
+var parallelSubtestCapture = &Check{
+	Name: "parallel-subtest-capture",
+	ID:   "TS0037",
+	Doc:  "report parallel subtests writing variables captured from the parent test",
+	Rationale: `A subtest calling t.Parallel runs alongside the other parallel subtests of
+its parent, after the parent's function has returned or moved on. Counters,
+fixtures and table entries it captures from the parent are shared with all
+of them, and writing one races with every other subtest doing the same.
+Before Go 1.22 the loop variable of a table-driven test is shared too, and
+the loop has usually moved past the entry by the time the subtest reads it.`,
+	Bad: `var ran int
+for _, tc := range tests {
+	t.Run(tc.name, func(t *testing.T) {
+		t.Parallel()
+		ran++
+		check(t, tc)
+	})
+}`,
+	Good: `var ran atomic.Int64
+for _, tc := range tests {
+	t.Run(tc.name, func(t *testing.T) {
+		t.Parallel()
+		ran.Add(1)
+		check(t, tc)
+	})
+}`,
+	Run: func(pass *Pass) {
+		helpers := callingFuncs(pass, func(fn *types.Func) bool { return isTestingMethod(fn, "Parallel") })
+		for _, f := range pass.Files {
+			shared := sharesLoopVars(pass, f)
+			// loopVars holds the variables declared by for and range
+			// clauses, which are per iteration from Go 1.22.
+			loopVars := map[types.Object]bool{}
+			ast.Inspect(f, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.ForStmt:
+					if init, ok := n.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE {
+						for _, lhs := range init.Lhs {
+							addLoopVar(pass.TypesInfo, loopVars, lhs)
+						}
+					}
+				case *ast.RangeStmt:
+					if n.Tok == token.DEFINE {
+						addLoopVar(pass.TypesInfo, loopVars, n.Key)
+						addLoopVar(pass.TypesInfo, loopVars, n.Value)
+					}
+				case *ast.CallExpr:
+					lit := subtestFunc(pass.TypesInfo, n)
+					if lit == nil || !callsParallel(pass.TypesInfo, lit.Body, helpers) || synchronizes(pass.TypesInfo, lit.Body) {
+						break
+					}
+					captured := func(obj types.Object) bool {
+						v, ok := obj.(*types.Var)
+						return ok && !v.IsField() && v.Parent() != nil && v.Parent() != v.Pkg().Scope() && (v.Pos() < lit.Pos() || v.Pos() >= lit.End())
+					}
+					reported := map[types.Object]bool{}
+					if shared {
+						for _, id := range capturedVars(pass.TypesInfo, lit) {
+							if obj := pass.TypesInfo.Uses[id]; loopVars[obj] {
+								reported[obj] = true
+								pass.Reportf(id, nil, "parallel subtest captures loop variable %s, which the loop changes before the subtest runs", id.Name)
+							}
+						}
+					}
+					check := func(lhs ast.Expr) {
+						obj := rootObject(pass.TypesInfo, lhs)
+						if obj == nil || reported[obj] || !captured(obj) {
+							return
+						}
+						if loopVars[obj] && !writesThrough(pass.TypesInfo, lhs) {
+							// Each subtest has its own copy of the entry.
+							return
+						}
+						reported[obj] = true
+						pass.Reportf(lhs, nil, "parallel subtest writes %s, which it shares with its parent and the subtests running alongside it", obj.Name())
+					}
+					ast.Inspect(lit.Body, func(n ast.Node) bool {
+						switch n := n.(type) {
+						case *ast.AssignStmt:
+							if n.Tok != token.DEFINE {
+								for _, lhs := range n.Lhs {
+									check(lhs)
+								}
+							}
+						case *ast.IncDecStmt:
+							check(n.X)
+						}
+						return true
+					})
+				}
+				return true
+			})
+		}
+	},
+}
+
+// subtestFunc returns the function literal a call of t.Run runs as a
+// subtest, or nil if call calls nothing of the kind.
+func subtestFunc(info *types.Info, call *ast.CallExpr) *ast.FuncLit {
+	fn, ok := calledFunc(info, call)
+	if !ok || !isTestingMethod(fn, "Run") || len(call.Args) != 2 {
+		return nil
+	}
+	lit, _ := ast.Unparen(call.Args[1]).(*ast.FuncLit)
+	return lit
+}
+
+// callsParallel reports whether body calls t.Parallel, or a function in
+// helpers, outside function literals.
+func callsParallel(info *types.Info, body *ast.BlockStmt, helpers map[*types.Func]bool) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			if fn, ok := calledFunc(info, n); ok && (helpers[fn] || isTestingMethod(fn, "Parallel")) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// isTestingMethod reports whether fn is the method of the testing package
+// named name.
+func isTestingMethod(fn *types.Func, name string) bool {
+	return fn.Name() == name && fn.Pkg() != nil && fn.Pkg().Path() == "testing" && fn.Type().(*types.Signature).Recv() != nil
+}
+
+// callingFuncs returns the functions declared in the package that call,
+// outside function literals, a function for which match is true or, in
+// turn, one of the functions returned.
+func callingFuncs(pass *Pass, match func(*types.Func) bool) map[*types.Func]bool {
+	bodies := map[*types.Func]*ast.BlockStmt{}
+	for _, f := range pass.Files {
+		for _, decl := range f.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil {
+				if fn, ok := pass.TypesInfo.Defs[fd.Name].(*types.Func); ok {
+					bodies[fn] = fd.Body
+				}
+			}
+		}
+	}
+	calling := map[*types.Func]bool{}
+	for changed := true; changed; {
+		changed = false
+		for fn, body := range bodies {
+			if calling[fn] {
+				continue
+			}
+			ast.Inspect(body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncLit:
+					return false
+				case *ast.CallExpr:
+					if callee, ok := calledFunc(pass.TypesInfo, n); ok && (calling[callee] || match(callee)) {
+						calling[fn] = true
+					}
+				}
+				return !calling[fn]
+			})
+			changed = changed || calling[fn]
+		}
+	}
+	return calling
+}
+
+// writesThrough reports whether assigning to expr writes memory reached
+// through a pointer, slice or map rather than the variable at its root.
+func writesThrough(info *types.Info, expr ast.Expr) bool {
+	for {
+		switch e := ast.Unparen(expr).(type) {
+		case *ast.SelectorExpr:
+			if sel := info.Selections[e]; sel != nil && sel.Indirect() {
+				return true
+			}
+			expr = e.X
+		case *ast.IndexExpr:
+			if _, ok := types.Unalias(info.TypeOf(e.X)).Underlying().(*types.Array); !ok {
+				return true
+			}
+			expr = e.X
+		case *ast.StarExpr:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+var parallelTestEnv = &Check{
+	Name: "parallel-test-env",
+	ID:   "TS0038",
+	Doc:  "report t.Setenv, t.Chdir and os.Chdir in parallel tests and their subtests",
+	Rationale: `The environment and the working directory belong to the process, not to a
+test. t.Setenv and t.Chdir panic in a test that is parallel or has a
+parallel ancestor, and os.Chdir there changes the directory under every
+test running alongside it. The test may become parallel through a helper
+calling t.Parallel, or through the test or subtest enclosing it.`,
+	Bad: `func TestConfig(t *testing.T) {
+	t.Parallel()
+	t.Setenv("HOME", t.TempDir()) // panics
+	load(t)
+}`,
+	Good: `func TestConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	load(t)
+}`,
+	Run: func(pass *Pass) {
+		helpers := callingFuncs(pass, func(fn *types.Func) bool { return isTestingMethod(fn, "Parallel") })
+		changesEnv := func(fn *types.Func) bool {
+			return isTestingMethod(fn, "Setenv") || isTestingMethod(fn, "Chdir") || fn.FullName() == "os.Chdir"
+		}
+		envHelpers := callingFuncs(pass, changesEnv)
+		var visit func(body *ast.BlockStmt, parallel bool)
+		visit = func(body *ast.BlockStmt, parallel bool) {
+			parallel = parallel || callsParallel(pass.TypesInfo, body, helpers)
+			ast.Inspect(body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				if lit := subtestFunc(pass.TypesInfo, call); lit != nil {
+					visit(lit.Body, parallel)
+					return false
+				}
+				fn, ok := calledFunc(pass.TypesInfo, call)
+				if !ok || !parallel {
+					return true
+				}
+				switch {
+				case fn.FullName() == "os.Chdir":
+					pass.Reportf(call, nil, "os.Chdir in a parallel test changes the working directory of every test running alongside it")
+				case changesEnv(fn):
+					pass.Reportf(call, nil, "%s in a parallel test panics", fn.Name())
+				case envHelpers[fn]:
+					pass.Reportf(call, nil, "%s changes the environment or working directory, which a parallel test may not", fn.Name())
+				}
+				return true
+			})
+		}
+		for _, f := range pass.Files {
+			if !strings.HasSuffix(pass.Fset.Position(f.Package).Filename, "_test.go") {
+				continue
+			}
+			for _, decl := range f.Decls {
+				if fd, ok := decl.(*ast.FuncDecl); ok && fd.Body != nil && strings.HasPrefix(fd.Name.Name, "Test") {
+					visit(fd.Body, false)
+				}
+			}
+		}
+	},
+}
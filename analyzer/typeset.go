@@ -0,0 +1,160 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/types"
+	"path"
+	"strings"
+)
+
+// A typeSet is a set of types given by name, pattern or marker interface,
+// such as the types configured as safe to share between goroutines.
+type typeSet struct {
+	// exact holds the entries naming a single type, spelled as
+	// types.TypeString spells it.
+	exact    map[string]bool
+	patterns []typePattern
+	// markers are the marker interfaces whose implementations belong to
+	// the set.
+	markers []*types.Interface
+}
+
+// A typePattern matches named types, or pointers to them, by package and
+// name:
+//
+//	*github.com/acme/metrics.*        pointers to any type of the package
+//	github.com/acme/proto/....Config  types named Config in the package or
+//	                                  any package below it
+//	*go.uber.org/zap.Sugared*         pointers to SugaredLogger and the like
+//	github.com/acme/immutable/...     every type of the packages
+//
+// The name is a path.Match pattern, and a package path ending in "/..."
+// also matches the packages below it, as in go list. A leading "*" matches
+// pointers to the types rather than the types themselves.
+type typePattern struct {
+	pointer     bool
+	pkg         string
+	subpackages bool
+	name        string
+}
+
+// newTypeSet parses entries such as those of Config.SafeTypes.
+func newTypeSet(entries []string) (*typeSet, error) {
+	s := &typeSet{exact: map[string]bool{}}
+	for _, entry := range entries {
+		pattern, ok, err := parseTypePattern(entry)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			s.patterns = append(s.patterns, pattern)
+		} else {
+			s.exact[entry] = true
+		}
+	}
+	return s, nil
+}
+
+// addMarkers resolves the interfaces named by Config.SafeInterfaces, such as
+// "github.com/mycorp/conc.ConcurrencySafe", among pkg and its dependencies.
+// Interfaces neither imports are skipped, since no type of the package can
+// implement them without reaching their package.
+func (s *typeSet) addMarkers(pkg *types.Package, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	packages := map[string]*types.Package{}
+	var visit func(*types.Package)
+	visit = func(p *types.Package) {
+		if packages[p.Path()] != nil {
+			return
+		}
+		packages[p.Path()] = p
+		for _, imported := range p.Imports() {
+			visit(imported)
+		}
+	}
+	visit(pkg)
+	for _, name := range names {
+		dot := strings.LastIndex(name, ".")
+		if dot < 0 || packages[name[:dot]] == nil {
+			continue
+		}
+		obj, ok := packages[name[:dot]].Scope().Lookup(name[dot+1:]).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		if iface, ok := obj.Type().Underlying().(*types.Interface); ok {
+			s.markers = append(s.markers, iface)
+		}
+	}
+}
+
+// parseTypePattern parses entry as a typePattern, returning ok false if it
+// names a single type instead.
+func parseTypePattern(entry string) (pattern typePattern, ok bool, err error) {
+	rest, pointer := strings.CutPrefix(entry, "*")
+	if pkg, ok := strings.CutSuffix(rest, "/..."); ok {
+		return typePattern{pointer: pointer, pkg: pkg, subpackages: true, name: "*"}, true, nil
+	}
+	dot := strings.LastIndex(rest, ".")
+	if dot <= 0 || strings.LastIndex(rest, "/") > dot {
+		return typePattern{}, false, nil
+	}
+	pkg, name := rest[:dot], rest[dot+1:]
+	pkg, subpackages := strings.CutSuffix(pkg, "/...")
+	if !subpackages && !strings.ContainsAny(name, `*?[\`) {
+		return typePattern{}, false, nil
+	}
+	if _, err := path.Match(name, ""); err != nil || name == "" {
+		return typePattern{}, false, fmt.Errorf("invalid safe type pattern %q", entry)
+	}
+	return typePattern{pointer: pointer, pkg: pkg, subpackages: subpackages, name: name}, true, nil
+}
+
+// matches reports whether t matches p.
+func (p typePattern) matches(t types.Type) bool {
+	if p.pointer {
+		ptr, ok := types.Unalias(t).(*types.Pointer)
+		if !ok {
+			return false
+		}
+		t = ptr.Elem()
+	}
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return false
+	}
+	pkg := named.Obj().Pkg().Path()
+	if pkg != p.pkg && !(p.subpackages && strings.HasPrefix(pkg, p.pkg+"/")) {
+		return false
+	}
+	ok, _ = path.Match(p.name, named.Obj().Name())
+	return ok
+}
+
+// contains reports whether t is in s.
+func (s *typeSet) contains(t types.Type) bool {
+	if s == nil {
+		return false
+	}
+	if s.exact[types.TypeString(t, nil)] {
+		return true
+	}
+	for _, pattern := range s.patterns {
+		if pattern.matches(t) {
+			return true
+		}
+	}
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		// Interface types only implement markers by embedding them, which
+		// says nothing about the dynamic values they hold.
+		return false
+	}
+	for _, marker := range s.markers {
+		if types.Implements(t, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+)
+
+// unsafeTypes lists, by check name, the types known not to be safe for
+// concurrent use that each category check reports. Config.UnsafeTypes adds
+// to the lists.
+var unsafeTypes = map[string][]string{
+	"shared-db-handle": {
+		"*database/sql.Tx",
+		"*database/sql.Rows",
+		"*database/sql.Row",
+	},
+	"shared-rand": {
+		"*math/rand.Rand",
+		"*math/rand/v2.Rand",
+		"*math/rand/v2.PCG",
+		"*math/rand/v2.ChaCha8",
+	},
+	"shared-buffer": {
+		"*bytes.Buffer",
+		"*bytes.Reader",
+		"*strings.Builder",
+		"*strings.Reader",
+		"*bufio.Reader",
+		"*bufio.Writer",
+		"*bufio.ReadWriter",
+		"*bufio.Scanner",
+	},
+	"shared-template": {
+		"*text/template.Template",
+		"*html/template.Template",
+	},
+}
+
+var sharedDBHandle = newUnsafeTypeCheck(&Check{
+	Name: "shared-db-handle",
+	ID:   "TS0011",
+	Doc:  "report transactions and result sets of database/sql handed to another goroutine",
+	Rationale: `A *sql.DB is safe for concurrent use, but the transactions and rows it hands
+out are not: a *sql.Tx runs its statements on a single connection, one at a
+time, and *sql.Rows holds a cursor that Next and Scan advance. Using them
+from two goroutines interleaves statements or corrupts the scan.`,
+	Bad: `tx, _ := db.Begin()
+go audit(tx)
+tx.Exec("UPDATE accounts SET ...")`,
+	Good: `go audit(db) // audit begins its own transaction
+tx, _ := db.Begin()
+tx.Exec("UPDATE accounts SET ...")`,
+})
+
+var sharedRand = newUnsafeTypeCheck(&Check{
+	Name: "shared-rand",
+	ID:   "TS0012",
+	Doc:  "report random number generators of math/rand handed to another goroutine",
+	Rationale: `Unlike the top-level functions of math/rand, a *rand.Rand created with
+rand.New is not safe for concurrent use: its state is updated without
+synchronization on every call.`,
+	Bad: `r := rand.New(rand.NewSource(seed))
+go shuffle(r, a)
+shuffle(r, b)`,
+	Good: `go shuffle(rand.New(rand.NewSource(seed)), a)
+shuffle(rand.New(rand.NewSource(seed+1)), b)`,
+})
+
+var sharedBuffer = newUnsafeTypeCheck(&Check{
+	Name: "shared-buffer",
+	ID:   "TS0013",
+	Doc:  "report bytes, strings and bufio buffers handed to another goroutine",
+	Rationale: `Buffers such as *bytes.Buffer, *strings.Builder and the readers and writers
+of bufio keep a position and a byte slice that every call updates. None of
+them synchronize, so two goroutines using one race on both.`,
+	Bad: `var buf bytes.Buffer
+go render(&buf)
+buf.WriteString("footer")`,
+	Good: `done := make(chan []byte)
+go func() {
+	var buf bytes.Buffer
+	render(&buf)
+	done <- buf.Bytes()
+}()`,
+})
+
+var sharedTemplate = newUnsafeTypeCheck(&Check{
+	Name:     "shared-template",
+	ID:       "TS0014",
+	Doc:      "report templates of text/template and html/template handed to another goroutine",
+	Severity: SeverityInfo,
+	Rationale: `Executing a parsed template from several goroutines is safe, but Parse,
+Funcs, AddParseTree and the like modify it and may not run concurrently with
+anything else. Sharing a template is only right once it is fully parsed.`,
+	Bad: `t := template.New("page")
+go t.Parse(header)
+t.Parse(body)`,
+	Good: `t := template.Must(template.New("page").Parse(header + body))
+go t.Execute(w, data)`,
+})
+
+// newUnsafeTypeSets returns the type sets of the checks in unsafeTypes,
+// extended with the entries of extra, keyed by check name or ID.
+func newUnsafeTypeSets(extra map[string][]string) (map[string]*typeSet, error) {
+	entries := map[string][]string{}
+	for name, list := range unsafeTypes {
+		entries[name] = append(entries[name], list...)
+	}
+	for key, list := range extra {
+		check := Lookup(key)
+		if check == nil || unsafeTypes[check.Name] == nil {
+			return nil, fmt.Errorf("unknown unsafe type category %q", key)
+		}
+		entries[check.Name] = append(entries[check.Name], list...)
+	}
+	sets := map[string]*typeSet{}
+	for name, list := range entries {
+		set, err := newTypeSet(list)
+		if err != nil {
+			return nil, err
+		}
+		sets[name] = set
+	}
+	return sets, nil
+}
+
+// newUnsafeTypeCheck sets the Run function of a check reporting values of
+// the types listed for it in unsafeTypes, and returns the check.
+func newUnsafeTypeCheck(check *Check) *Check {
+	check.Run = func(pass *Pass) {
+		set := pass.unsafeTypeSets[check.Name]
+		if set == nil {
+			return
+		}
+		match := func(expr ast.Expr) types.Type {
+			return findType(pass.TypesInfo.TypeOf(expr), set.contains)
+		}
+		pass.Inspector.Preorder([]ast.Node{(*ast.SendStmt)(nil), (*ast.GoStmt)(nil)}, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.SendStmt:
+				if t := match(n.Value); t != nil {
+					pass.Reportf(n.Value, t, "sending value not safe for concurrent use over a channel")
+				}
+			case *ast.GoStmt:
+				if sel, ok := ast.Unparen(n.Call.Fun).(*ast.SelectorExpr); ok && pass.TypesInfo.Selections[sel] != nil {
+					if t := match(sel.X); t != nil {
+						pass.Reportf(sel.X, t, "calling goroutine on a value not safe for concurrent use")
+					}
+				}
+				for _, arg := range n.Call.Args {
+					if t := match(arg); t != nil {
+						pass.Reportf(arg, t, "calling goroutine with a value not safe for concurrent use")
+					}
+				}
+				if lit, ok := ast.Unparen(n.Call.Fun).(*ast.FuncLit); ok {
+					for _, id := range capturedVars(pass.TypesInfo, lit) {
+						// Captured variables are shared by reference.
+						t := match(id)
+						if ptr := types.NewPointer(pass.TypesInfo.TypeOf(id)); t == nil && set.contains(ptr) {
+							t = ptr
+						}
+						if t != nil {
+							pass.Reportf(id, t, "goroutine captures value not safe for concurrent use")
+						}
+					}
+				}
+			}
+		})
+	}
+	return check
+}
+
+// findType returns t or the first type within it, through struct fields,
+// array elements and aliases but not pointers, for which match is true, or
+// nil if there is none.
+func findType(t types.Type, match func(types.Type) bool) types.Type {
+	if t == nil {
+		return nil
+	}
+	if match(t) {
+		return t
+	}
+	switch u := types.Unalias(t).Underlying().(type) {
+	case *types.Array:
+		return findType(u.Elem(), match)
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if found := findType(u.Field(i).Type(), match); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// capturedVars returns the first use in lit of each local variable it
+// captures from the enclosing function.
+func capturedVars(info *types.Info, lit *ast.FuncLit) []*ast.Ident {
+	var captured []*ast.Ident
+	seen := map[types.Object]bool{}
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		v, ok := info.Uses[id].(*types.Var)
+		if !ok || v.IsField() || seen[v] || v.Parent() == nil || v.Parent() == v.Pkg().Scope() {
+			return true
+		}
+		if v.Pos() < lit.Pos() || v.Pos() >= lit.End() {
+			seen[v] = true
+			captured = append(captured, id)
+		}
+		return true
+	})
+	return captured
+}
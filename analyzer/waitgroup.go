@@ -0,0 +1,153 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+var waitGroupByValue = &Check{
+	Name: "waitgroup-by-value",
+	ID:   "TS0043",
+	Doc:  "report parameters and arguments passing a sync.WaitGroup, or a struct containing one, by value",
+	Rationale: `A function taking a sync.WaitGroup by value gets a copy of its counter.
+Done decrements the copy, the caller's Wait never sees it, and the program
+deadlocks, or Add counts a goroutine the caller does not wait for. Pass a
+*sync.WaitGroup, or a pointer to the struct holding it.`,
+	Bad: `func worker(wg sync.WaitGroup, jobs <-chan Job) {
+	defer wg.Done() // decrements a copy
+	...
+}`,
+	Good: `func worker(wg *sync.WaitGroup, jobs <-chan Job) {
+	defer wg.Done()
+	...
+}`,
+	Run: func(pass *Pass) {
+		describe := func(t types.Type) string {
+			if isWaitGroup(t) {
+				return "a WaitGroup"
+			}
+			if path := typePath(t, isWaitGroup); path != "" {
+				return "the WaitGroup " + path
+			}
+			return ""
+		}
+		pass.Inspector.Preorder([]ast.Node{(*ast.FuncType)(nil), (*ast.CallExpr)(nil)}, func(n ast.Node) {
+			switch n := n.(type) {
+			case *ast.FuncType:
+				if n.Params == nil {
+					break
+				}
+				for _, field := range n.Params.List {
+					if what := describe(pass.TypesInfo.TypeOf(field.Type)); what != "" {
+						pass.Reportf(field.Type, nil, "parameter takes a copy of %s; take a pointer so that Done and Wait share one counter", what)
+					}
+				}
+			case *ast.CallExpr:
+				if pass.TypesInfo.Types[n.Fun].IsType() {
+					break
+				}
+				for _, arg := range n.Args {
+					if _, ok := ast.Unparen(arg).(*ast.CompositeLit); ok {
+						continue
+					}
+					if what := describe(pass.TypesInfo.TypeOf(arg)); what != "" {
+						pass.Reportf(arg, nil, "passing %s by value copies its counter", what)
+					}
+				}
+			}
+		})
+	},
+}
+
+var addInGoroutine = &Check{
+	Name: "add-in-goroutine",
+	ID:   "TS0044",
+	Doc:  "report sync.WaitGroup.Add called inside a goroutine whose spawner waits on the same WaitGroup",
+	Rationale: `Wait returns as soon as the counter is zero. When the goroutine calls Add
+itself, the spawner may reach Wait before the goroutine has started, see
+nothing to wait for and return, leaving the goroutine running. Add must
+happen before the go statement, on the spawner's side.`,
+	Bad: `for _, job := range jobs {
+	go func() {
+		wg.Add(1)
+		defer wg.Done()
+		job.Run()
+	}()
+}
+wg.Wait()`,
+	Good: `for _, job := range jobs {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		job.Run()
+	}()
+}
+wg.Wait()`,
+	Run: func(pass *Pass) {
+		pass.Inspector.Preorder([]ast.Node{(*ast.GoStmt)(nil)}, func(n ast.Node) {
+			stmt := n.(*ast.GoStmt)
+			lit, ok := ast.Unparen(stmt.Call.Fun).(*ast.FuncLit)
+			if !ok {
+				return
+			}
+			var body *ast.BlockStmt
+			switch spawner := funcOf(enclosingPath(pass, stmt)).(type) {
+			case *ast.FuncLit:
+				body = spawner.Body
+			case *ast.FuncDecl:
+				body = spawner.Body
+			default:
+				return
+			}
+			// waited holds the WaitGroups the spawner waits on outside the
+			// goroutine.
+			waited := map[types.Object]bool{}
+			ast.Inspect(body, func(n ast.Node) bool {
+				if n == stmt {
+					return false
+				}
+				if call, ok := n.(*ast.CallExpr); ok {
+					if recv := waitGroupCall(pass.TypesInfo, call, "Wait"); recv != nil {
+						waited[rootObject(pass.TypesInfo, recv)] = true
+					}
+				}
+				return true
+			})
+			ast.Inspect(lit.Body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.FuncLit:
+					return false
+				case *ast.CallExpr:
+					if recv := waitGroupCall(pass.TypesInfo, n, "Add"); recv != nil && waited[rootObject(pass.TypesInfo, recv)] {
+						pass.Reportf(n, nil, "Add called inside the goroutine races with Wait in its spawner, which may return first; call Add before the go statement")
+					}
+				}
+				return true
+			})
+		})
+	},
+}
+
+// waitGroupCall returns the receiver of call if it calls the method of
+// sync.WaitGroup named name, or nil.
+func waitGroupCall(info *types.Info, call *ast.CallExpr, name string) ast.Expr {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return nil
+	}
+	fn, ok := calledFunc(info, call)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "sync" || fn.Type().(*types.Signature).Recv() == nil {
+		return nil
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if ptr, ok := types.Unalias(recv.Type()).(*types.Pointer); !ok || !isWaitGroup(ptr.Elem()) {
+		return nil
+	}
+	return sel.X
+}
+
+// isWaitGroup reports whether t is sync.WaitGroup.
+func isWaitGroup(t types.Type) bool {
+	named, ok := types.Unalias(t).(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "sync" && named.Obj().Name() == "WaitGroup"
+}
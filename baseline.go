@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rpetrich/tsgo/checker"
+)
+
+// A baseline records existing findings so that only new ones are reported.
+// Findings are matched by fingerprint rather than position, so that edits
+// elsewhere in a file do not resurrect them.
+type baseline struct {
+	Version  int               `json:"version"`
+	Findings []baselineFinding `json:"findings"`
+}
+
+type baselineFinding struct {
+	Fingerprint string `json:"fingerprint"`
+	Check       string `json:"check"`
+	File        string `json:"file"`
+	// Line and Message only help people reading the file; matching ignores
+	// them.
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// fingerprint identifies a finding across unrelated edits: it covers the
+// check, the file relative to the baseline, the message and the offending
+// source with whitespace collapsed, but not the position, nor those of other
+// places the message names.
+func fingerprint(d checker.Diagnostic, file string) string {
+	h := sha256.New()
+	for _, part := range []string{d.Check, file, d.StableString(), strings.Join(strings.Fields(d.Node), " ")} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// newBaseline records diagnostics, with file names relative to dir.
+func newBaseline(diagnostics []checker.Diagnostic, dir string) *baseline {
+	b := &baseline{Version: 1, Findings: []baselineFinding{}}
+	for _, d := range diagnostics {
+		file := relativeTo(dir, d.Pos.Filename)
+		b.Findings = append(b.Findings, baselineFinding{
+			Fingerprint: fingerprint(d, file),
+			Check:       d.Check,
+			File:        file,
+			Line:        d.Pos.Line,
+			Message:     d.String(),
+		})
+	}
+	sort.SliceStable(b.Findings, func(i, j int) bool {
+		if b.Findings[i].File != b.Findings[j].File {
+			return b.Findings[i].File < b.Findings[j].File
+		}
+		return b.Findings[i].Line < b.Findings[j].Line
+	})
+	return b
+}
+
+func readBaseline(path string) (*baseline, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b := &baseline{}
+	if err := json.Unmarshal(contents, b); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if b.Version != 1 {
+		return nil, fmt.Errorf("%s: unsupported baseline version %d", path, b.Version)
+	}
+	return b, nil
+}
+
+// filterBaseline returns the diagnostics not recorded in the baseline at
+// path, and how many were suppressed. Each recorded finding suppresses at most
+// one diagnostic, so a second copy of a known problem is still reported.
+func filterBaseline(diagnostics []checker.Diagnostic, path string) ([]checker.Diagnostic, int, error) {
+	b, err := readBaseline(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, 0, err
+	}
+	known := map[string]int{}
+	for _, f := range b.Findings {
+		known[f.Fingerprint]++
+	}
+	var kept []checker.Diagnostic
+	suppressed := 0
+	for _, d := range diagnostics {
+		if fp := fingerprint(d, relativeTo(dir, d.Pos.Filename)); known[fp] > 0 {
+			known[fp]--
+			suppressed++
+			continue
+		}
+		kept = append(kept, d)
+	}
+	return kept, suppressed, nil
+}
+
+// baselineMain implements tsgo baseline write FILE, recording the current
+// findings of the packages named by the remaining arguments. It does not
+// return.
+func baselineMain(args []string) {
+	flags := flag.NewFlagSet("baseline", flag.ExitOnError)
+	var opts loadOptions
+	opts.register(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: tsgo baseline write FILE [flags] [packages | files]\n\n")
+		flags.PrintDefaults()
+	}
+	if len(args) < 2 || args[0] != "write" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	path := args[1]
+	flags.Parse(args[2:])
+	if err := opts.validate(flags.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "tsgo: %v\n", err)
+		os.Exit(2)
+	}
+	// The baseline records everything, including what an older one hid.
+	opts.baseline = ""
+	r, err := analyze(&opts, flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tsgo: %v\n", err)
+		os.Exit(2)
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		panic(err)
+	}
+	contents, err := json.MarshalIndent(newBaseline(r.Diagnostics, dir), "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(path, append(contents, '\n'), 0o666); err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(os.Stderr, "tsgo: recorded %d findings in %s\n", len(r.Diagnostics), path)
+	os.Exit(0)
+}
@@ -0,0 +1,271 @@
+// Package checker runs tsgo's checks over loaded packages and returns the
+// findings as structured values, for programs that embed tsgo rather than
+// parse its output.
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"github.com/rpetrich/tsgo/analyzer"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+)
+
+// Options controls which checks Check runs.
+type Options struct {
+	// Enable lists the checks to run; when empty, all checks on by default
+	// run, along with those listed in Include.
+	Enable  []string
+	Include []string
+	// Disable lists checks not to run.
+	Disable []string
+	// Severity overrides the default severity of checks, keyed by check
+	// name or ID.
+	Severity map[string]analyzer.Severity
+	// NestedSeverity, if set, is the severity of findings whose pointer is
+	// nested in a struct or array payload, for checks without an entry in
+	// Severity.
+	NestedSeverity analyzer.Severity
+	// SafeTypes lists fully qualified types and type patterns that are safe
+	// to share between goroutines, as in analyzer.Config.
+	SafeTypes []string
+	// SafeInterfaces lists marker interfaces whose implementations are safe
+	// to share, as in analyzer.Config.
+	SafeInterfaces []string
+	// Sanitizers lists functions returning or filling in fresh copies of
+	// values, as in analyzer.Config.
+	Sanitizers []string
+	// AsyncCallbacks lists functions and func types whose function
+	// arguments are called later on another goroutine, as in
+	// analyzer.Config.
+	AsyncCallbacks []string
+	// SpawnWrappers lists functions starting goroutines on behalf of their
+	// callers, as in analyzer.Config.
+	SpawnWrappers []string
+	// BlockingFuncs lists functions that may block for a long time, as in
+	// analyzer.Config.
+	BlockingFuncs []string
+	// UnsafeTypes adds types known not to be safe for concurrent use, keyed
+	// by the check reporting them, as in analyzer.Config.
+	UnsafeTypes map[string][]string
+	// MinCriticalSection is the number of statements between a Lock and its
+	// Unlock up to which undeferred-unlock stays quiet, as in
+	// analyzer.Config.
+	MinCriticalSection int
+	// StrictSuppressions requires //tsgo:ignore directives to name a check
+	// and give a reason, as in analyzer.Config.
+	StrictSuppressions bool
+}
+
+// Diagnostic is a single finding.
+type Diagnostic struct {
+	Pos, End token.Position
+	// Package is the import path of the package the finding is in.
+	Package string
+	// Check is the name of the check reporting the finding, and ID its
+	// stable code, if it has one.
+	Check string
+	ID    string
+	// Severity is how serious the finding is.
+	Severity analyzer.Severity
+	// Message describes the problem.
+	Message string
+	// StableMessage is Message without any positions of other places it
+	// names, which move when unrelated code does, or empty when the same.
+	StableMessage string
+	// Type is the offending type, or nil if the finding is not about a type.
+	Type types.Type
+	// Node is the source text of the offending expression or declaration.
+	Node string
+	// Nested is set when the pointer reported is nested in a struct or
+	// array payload rather than being the payload itself.
+	Nested bool
+	// Fixes are the suggested fixes for the finding, with edits expressed
+	// as byte offsets into the named files.
+	Fixes []Fix
+	// Related are other places involved in the finding.
+	Related []Related
+}
+
+// Related is a place involved in a finding other than where it is reported.
+type Related struct {
+	Pos     token.Position
+	Message string
+}
+
+// Fix is a suggested fix: a set of edits that together resolve a finding.
+// Only Safe fixes are meant to be applied without review.
+type Fix struct {
+	Message string
+	Edits   []Edit
+	Safe    bool
+}
+
+// Edit replaces the bytes [Start, End) of Filename with New.
+type Edit struct {
+	Filename   string
+	Start, End int
+	New        string
+}
+
+// String returns the message followed by the offending type or, lacking one,
+// the offending source.
+func (d Diagnostic) String() string {
+	var annotation interface{}
+	if d.Type != nil {
+		annotation = d.Type
+	} else {
+		annotation = d.Node
+	}
+	return fmt.Sprintf("%s (%v)", d.Message, annotation)
+}
+
+// StableString is String with StableMessage in place of Message, for
+// identifying the finding across edits.
+func (d Diagnostic) StableString() string {
+	if d.StableMessage != "" {
+		d.Message = d.StableMessage
+	}
+	return d.String()
+}
+
+// Check runs the checks selected by opts over pkgs, which must have been
+// loaded with at least LoadMode, as Load does. Packages that fail to analyze
+// are reported in the returned error; diagnostics from the others are still
+// returned, in the order of SortDiagnostics.
+func Check(pkgs []*packages.Package, opts Options) ([]Diagnostic, error) {
+	a, err := analyzer.New(analyzer.Config{
+		Enable:             opts.Enable,
+		Include:            opts.Include,
+		Disable:            opts.Disable,
+		SafeTypes:          opts.SafeTypes,
+		SafeInterfaces:     opts.SafeInterfaces,
+		Sanitizers:         opts.Sanitizers,
+		AsyncCallbacks:     opts.AsyncCallbacks,
+		SpawnWrappers:      opts.SpawnWrappers,
+		BlockingFuncs:      opts.BlockingFuncs,
+		UnsafeTypes:        opts.UnsafeTypes,
+		MinCriticalSection: opts.MinCriticalSection,
+		StrictSuppressions: opts.StrictSuppressions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	severities := map[string]analyzer.Severity{}
+	for key, severity := range opts.Severity {
+		check := analyzer.Lookup(key)
+		if check == nil {
+			return nil, fmt.Errorf("unknown check %q", key)
+		}
+		if err := severity.Validate(); err != nil {
+			return nil, err
+		}
+		severities[check.Name] = severity
+	}
+	if opts.NestedSeverity != "" {
+		if err := opts.NestedSeverity.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	graph, err := checker.Analyze([]*analysis.Analyzer{a}, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+	var diagnostics []Diagnostic
+	var errs []error
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", act.Package.PkgPath, act.Err))
+			continue
+		}
+		for _, finding := range act.Result.([]*analyzer.Finding) {
+			severity := severityOf(finding.Check, severities)
+			if _, ok := severities[finding.Check.Name]; !ok && finding.Nested && opts.NestedSeverity != "" {
+				severity = opts.NestedSeverity
+			}
+			diagnostics = append(diagnostics, Diagnostic{
+				Package:       act.Package.PkgPath,
+				Pos:           act.Package.Fset.Position(finding.Pos),
+				End:           act.Package.Fset.Position(finding.End),
+				Check:         finding.Check.Name,
+				ID:            finding.Check.ID,
+				Severity:      severity,
+				Message:       finding.Message,
+				StableMessage: finding.StableMessage,
+				Type:          finding.Type,
+				Node:          finding.Node,
+				Nested:        finding.Nested,
+				Fixes:         convertFixes(act.Package.Fset, finding.Fixes),
+				Related:       convertRelated(act.Package.Fset, finding.Related),
+			})
+		}
+	}
+	SortDiagnostics(diagnostics)
+	return diagnostics, errors.Join(errs...)
+}
+
+func severityOf(check *analyzer.Check, severities map[string]analyzer.Severity) analyzer.Severity {
+	if severity, ok := severities[check.Name]; ok {
+		return severity
+	}
+	return check.DefaultSeverity()
+}
+
+// SortDiagnostics sorts diagnostics by file, line and column, then by check
+// ID and name, so that output does not depend on the order in which packages
+// and checks happened to run.
+func SortDiagnostics(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		switch {
+		case a.Pos.Filename != b.Pos.Filename:
+			return a.Pos.Filename < b.Pos.Filename
+		case a.Pos.Line != b.Pos.Line:
+			return a.Pos.Line < b.Pos.Line
+		case a.Pos.Column != b.Pos.Column:
+			return a.Pos.Column < b.Pos.Column
+		case a.ID != b.ID:
+			return a.ID < b.ID
+		case a.Check != b.Check:
+			return a.Check < b.Check
+		}
+		return a.Message < b.Message
+	})
+}
+
+func convertFixes(fset *token.FileSet, fixes []analyzer.Fix) []Fix {
+	var converted []Fix
+	for _, fix := range fixes {
+		edits := make([]Edit, 0, len(fix.TextEdits))
+		for _, edit := range fix.TextEdits {
+			end := edit.End
+			if !end.IsValid() {
+				end = edit.Pos
+			}
+			// Edits apply to the file as parsed, not to whatever a //line
+			// directive maps it back to.
+			start := fset.PositionFor(edit.Pos, false)
+			edits = append(edits, Edit{
+				Filename: start.Filename,
+				Start:    start.Offset,
+				End:      fset.PositionFor(end, false).Offset,
+				New:      string(edit.NewText),
+			})
+		}
+		converted = append(converted, Fix{Message: fix.Message, Edits: edits, Safe: fix.Safe})
+	}
+	return converted
+}
+
+func convertRelated(fset *token.FileSet, related []analysis.RelatedInformation) []Related {
+	var converted []Related
+	for _, r := range related {
+		converted = append(converted, Related{Pos: fset.Position(r.Pos), Message: r.Message})
+	}
+	return converted
+}
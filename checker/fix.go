@@ -0,0 +1,94 @@
+package checker
+
+import (
+	"fmt"
+	"go/format"
+	"sort"
+)
+
+// ApplyFixes computes the contents of every file touched by the first
+// applicable fix of each diagnostic. Unsafe fixes are only considered when
+// unsafe is set. A fix whose edits overlap those of a fix already accepted is
+// skipped and counted in skipped, since applying both would corrupt the file;
+// edits identical to accepted ones are not conflicts, as fixes for related
+// findings often share them.
+// The result is gofmt-formatted where it parses, and readFile supplies the
+// current contents of each file.
+func ApplyFixes(diagnostics []Diagnostic, unsafe bool, readFile func(string) ([]byte, error)) (fixed map[string][]byte, applied, skipped int, err error) {
+	accepted := map[string][]Edit{}
+	for _, d := range diagnostics {
+		for _, fix := range d.Fixes {
+			if !fix.Safe && !unsafe {
+				continue
+			}
+			if overlapsAccepted(accepted, fix.Edits) {
+				skipped++
+				break
+			}
+			for _, edit := range fix.Edits {
+				if !containsEdit(accepted[edit.Filename], edit) {
+					accepted[edit.Filename] = append(accepted[edit.Filename], edit)
+				}
+			}
+			applied++
+			break
+		}
+	}
+
+	fixed = map[string][]byte{}
+	for filename, edits := range accepted {
+		src, err := readFile(filename)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		out, err := applyEdits(src, edits)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("%s: %v", filename, err)
+		}
+		if formatted, err := format.Source(out); err == nil {
+			out = formatted
+		}
+		fixed[filename] = out
+	}
+	return fixed, applied, skipped, nil
+}
+
+func overlapsAccepted(accepted map[string][]Edit, edits []Edit) bool {
+	for _, edit := range edits {
+		for _, other := range accepted[edit.Filename] {
+			if edit == other {
+				continue
+			}
+			if edit.Start < other.End && other.Start < edit.End ||
+				edit.Start == other.Start && edit.End == other.End {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsEdit(edits []Edit, edit Edit) bool {
+	for _, other := range edits {
+		if other == edit {
+			return true
+		}
+	}
+	return false
+}
+
+func applyEdits(src []byte, edits []Edit) ([]byte, error) {
+	sorted := append([]Edit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	var out []byte
+	last := 0
+	for _, edit := range sorted {
+		if edit.Start < last || edit.End > len(src) || edit.Start > edit.End {
+			return nil, fmt.Errorf("invalid edit at offset %d", edit.Start)
+		}
+		out = append(out, src[last:edit.Start]...)
+		out = append(out, edit.New...)
+		last = edit.End
+	}
+	return append(out, src[last:]...), nil
+}
@@ -0,0 +1,106 @@
+package checker
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// LoadMode requests syntax and type information for the named packages and,
+// because the analyzers exchange facts, for all of their dependencies too.
+// Load needs less, but Check accepts packages loaded with LoadMode by other
+// means.
+const LoadMode = packages.NeedName |
+	packages.NeedFiles |
+	packages.NeedCompiledGoFiles |
+	packages.NeedImports |
+	packages.NeedDeps |
+	packages.NeedTypes |
+	packages.NeedTypesSizes |
+	packages.NeedSyntax |
+	packages.NeedTypesInfo
+
+// Load loads the packages matching patterns in the form Check expects, using
+// cfg for everything but the mode and parser. Only the packages whose source
+// can carry facts are type-checked from source: those named, those of the
+// main module, or of the modules of the workspace, which keep their function
+// bodies so that facts about them reach the named packages whether or not
+// they are named too, and those of other modules annotating types with
+// //tsgo:threadsafe, whose declarations are enough. Packages from export
+// data take no part in the analysis: their imports are left out of the
+// graph.
+func Load(cfg *packages.Config, patterns ...string) ([]*packages.Package, error) {
+	listCfg := *cfg
+	listCfg.Mode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedImports | packages.NeedDeps | packages.NeedModule
+	listed, err := packages.Load(&listCfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	roots := map[string]bool{}
+	for _, pkg := range listed {
+		roots[pkg.ID] = true
+	}
+	keepBodies := map[string]bool{}
+	sourcePatterns := append([]string(nil), patterns...)
+	packages.Visit(listed, nil, func(pkg *packages.Package) {
+		switch {
+		case roots[pkg.ID]:
+		case pkg.Module != nil && pkg.Module.Main:
+			sourcePatterns = append(sourcePatterns, pkg.PkgPath)
+		case pkg.Module != nil && annotatesTypes(pkg):
+			sourcePatterns = append(sourcePatterns, pkg.PkgPath)
+			return
+		default:
+			return
+		}
+		for _, path := range pkg.CompiledGoFiles {
+			keepBodies[path] = true
+		}
+	})
+
+	loadCfg := *cfg
+	loadCfg.Mode = LoadMode &^ packages.NeedDeps
+	loadCfg.ParseFile = func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+		f, err := parser.ParseFile(fset, filename, src, parser.AllErrors|parser.ParseComments|parser.SkipObjectResolution)
+		if f != nil && !keepBodies[filename] {
+			for _, decl := range f.Decls {
+				if fd, ok := decl.(*ast.FuncDecl); ok {
+					fd.Body = nil
+				}
+			}
+		}
+		return f, err
+	}
+	loaded, err := packages.Load(&loadCfg, sourcePatterns...)
+	if err != nil {
+		return nil, err
+	}
+	var pkgs []*packages.Package
+	for _, pkg := range loaded {
+		if roots[pkg.ID] {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		if pkg.Syntax == nil && pkg.Types != nil {
+			pkg.Imports = nil
+		}
+	})
+	return pkgs, nil
+}
+
+// annotatesTypes reports whether a file of pkg may hold a //tsgo:threadsafe
+// directive, whose facts only the package's source provides.
+func annotatesTypes(pkg *packages.Package) bool {
+	for _, path := range pkg.GoFiles {
+		src, err := os.ReadFile(path)
+		if err == nil && bytes.Contains(src, []byte("//tsgo:threadsafe")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+)
+
+type checkstyleReport struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr,omitempty"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// writeCheckstyle writes the report in the XML format of checkstyle, with a
+// file element per file that has findings and the check ID as the source of
+// each error. Notes have no place in the format and are left out.
+func writeCheckstyle(w io.Writer, r *report) error {
+	byFile := map[string]*checkstyleFile{}
+	var files []*checkstyleFile
+	for _, d := range r.Diagnostics {
+		f := byFile[d.Pos.Filename]
+		if f == nil {
+			f = &checkstyleFile{Name: d.Pos.Filename}
+			byFile[d.Pos.Filename] = f
+			files = append(files, f)
+		}
+		f.Errors = append(f.Errors, checkstyleError{
+			Line:     d.Pos.Line,
+			Column:   d.Pos.Column,
+			Severity: string(d.Severity),
+			Message:  d.String(),
+			Source:   ruleID(d.ID, d.Check),
+		})
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	out := checkstyleReport{Version: "5.0"}
+	for _, f := range files {
+		out.Files = append(out.Files, *f)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rpetrich/tsgo/analyzer"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileName is the name of the project configuration file, looked up in
+// the analyzed directory and its parents.
+const configFileName = ".tsgo.yaml"
+
+// fileConfig is the contents of a configuration file:
+//
+//	preset: relaxed
+//	enable: [chan-pointer-send, TS0003]
+//	disable: [global-var]
+//	severity:
+//	  chan-pointer-send: error
+//	exclude: [internal/gen, "*_mock.go"]
+//	safe-types: ["*go.uber.org/zap.Logger", "*github.com/acme/metrics.*"]
+//	safe-interfaces: [github.com/acme/conc.ConcurrencySafe]
+//	sanitizers: [google.golang.org/protobuf/proto.Clone]
+//	async-callbacks: [github.com/acme/sched.Every]
+//	spawn-wrappers: ["(*github.com/acme/workers.Pool).Submit"]
+//	blocking-funcs: ["(*github.com/acme/rpc.Client).Call"]
+//	unsafe-types:
+//	  shared-buffer: ["*github.com/acme/wire.Encoder"]
+//	min-critical-section: 2
+//	format: json
+//
+// Checks are named by name, ID or group and safe types by fully qualified name or
+// pattern. Safe interfaces, whose implementations are safe to share,
+// sanitizers, functions returning or filling in fresh copies, async
+// callbacks, functions calling their function arguments on another goroutine,
+// spawn wrappers, functions starting goroutines, and blocking funcs,
+// functions that may block for a long time, are named by fully qualified
+// name. Unsafe types, not safe for concurrent use, are added to the category
+// check reporting them. The minimum critical section is the number of
+// statements between a Lock and its Unlock up to which undeferred-unlock
+// stays quiet. Exclude patterns are matched relative to the directory
+// holding the file. Command-line flags take precedence.
+type fileConfig struct {
+	Preset             string                       `yaml:"preset"`
+	Enable             []string                     `yaml:"enable"`
+	Disable            []string                     `yaml:"disable"`
+	Severity           map[string]analyzer.Severity `yaml:"severity"`
+	Exclude            []string                     `yaml:"exclude"`
+	SafeTypes          []string                     `yaml:"safe-types"`
+	SafeInterfaces     []string                     `yaml:"safe-interfaces"`
+	Sanitizers         []string                     `yaml:"sanitizers"`
+	AsyncCallbacks     []string                     `yaml:"async-callbacks"`
+	SpawnWrappers      []string                     `yaml:"spawn-wrappers"`
+	BlockingFuncs      []string                     `yaml:"blocking-funcs"`
+	UnsafeTypes        map[string][]string          `yaml:"unsafe-types"`
+	MinCriticalSection int                          `yaml:"min-critical-section"`
+	Format             string                       `yaml:"format"`
+
+	// dir is the directory holding the file.
+	dir string
+}
+
+// findConfig returns the path of the configuration file governing dir: the
+// first .tsgo.yaml found in dir or one of its parents, or "" if there is none.
+func findConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		candidate := filepath.Join(dir, configFileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// configDir returns the directory from which the configuration file is
+// looked up: that of the first argument naming a local directory or file, or
+// the working directory.
+func configDir(opts *loadOptions, args []string) string {
+	if opts.stdin {
+		return filepath.Dir(opts.stdinFilename)
+	}
+	if len(args) > 0 {
+		arg := strings.TrimSuffix(args[0], "/...")
+		if info, err := os.Stat(arg); err == nil {
+			if info.IsDir() {
+				return arg
+			}
+			return filepath.Dir(arg)
+		}
+	}
+	return "."
+}
+
+// loadConfig reads the configuration file at path, rejecting unknown keys,
+// unknown checks and invalid severities.
+func loadConfig(path string) (*fileConfig, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &fileConfig{}
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := validatePreset(config.Preset); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for _, names := range [][]string{config.Enable, config.Disable} {
+		for _, name := range names {
+			if analyzer.Resolve(name) == nil {
+				return nil, fmt.Errorf("%s: unknown check %q", path, name)
+			}
+		}
+	}
+	for name, severity := range config.Severity {
+		if analyzer.Lookup(name) == nil {
+			return nil, fmt.Errorf("%s: unknown check %q", path, name)
+		}
+		if err := severity.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", path, name, err)
+		}
+	}
+	if _, err := analyzer.New(analyzer.Config{SafeTypes: config.SafeTypes, UnsafeTypes: config.UnsafeTypes}); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if config.Format != "" {
+		if _, ok := formatters[config.Format]; !ok {
+			return nil, fmt.Errorf("%s: unknown format %q", path, config.Format)
+		}
+	}
+	config.dir, err = filepath.Abs(filepath.Dir(path))
+	return config, err
+}
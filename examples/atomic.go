@@ -0,0 +1,45 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type requestStats struct {
+	served int64
+}
+
+func (s *requestStats) record() {
+	atomic.AddInt64(&s.served, 1)
+}
+
+func (s *requestStats) total() int64 {
+	return s.served
+}
+
+func newRequestStats(initial int64) *requestStats {
+	s := &requestStats{}
+	s.served = initial
+	return s
+}
+
+func countServed(handlers []func(*requestStats)) int64 {
+	var wg sync.WaitGroup
+	s := new(requestStats)
+	for _, handle := range handlers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handle(s)
+			s.record()
+		}()
+	}
+	wg.Wait()
+	return s.served
+}
+
+func resetAndShare(share func(*requestStats)) {
+	s := &requestStats{}
+	share(s)
+	s.served = 0
+}
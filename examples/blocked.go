@@ -0,0 +1,35 @@
+package main
+
+func leak(values []int) int {
+	results := make(chan int)
+	quit := make(chan struct{})
+	total := 0
+	go func() {
+		for r := range results {
+			total += r
+		}
+	}()
+	go func() {
+		select {
+		case <-quit:
+		case r := <-results:
+			total += r
+		}
+	}()
+	done := make(chan bool)
+	go func() {
+		<-done
+	}()
+	close(done)
+	fed := make(chan int)
+	go func() {
+		for r := range fed {
+			total += r
+		}
+	}()
+	for _, v := range values {
+		fed <- v
+	}
+	close(fed)
+	return total
+}
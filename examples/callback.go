@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type poller struct {
+	ticks int
+}
+
+func (p *poller) tick() { p.ticks++ }
+
+func callbacks(ctx context.Context, s *State) {
+	count := 0
+	time.AfterFunc(time.Second, func() {
+		count++
+	})
+	fmt.Println(count)
+	context.AfterFunc(ctx, func() {
+		fmt.Println(s.Name)
+	})
+	var p poller
+	time.AfterFunc(time.Second, p.tick)
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, s.Name)
+	})
+}
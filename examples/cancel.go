@@ -0,0 +1,45 @@
+package main
+
+import "context"
+
+type consumer struct {
+	jobs chan int
+}
+
+func (w *consumer) loop(ctx context.Context) {
+	for j := range w.jobs {
+		_ = j
+	}
+}
+
+func startWorkers(ctx context.Context, w *consumer) {
+	go w.loop(ctx)
+	go func() {
+		for {
+			select {
+			case j := <-w.jobs:
+				_ = j
+			}
+		}
+	}()
+	go func() {
+		for {
+			select {
+			case j := <-w.jobs:
+				_ = j
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (w *consumer) audit(j int) {}
+
+func handle(ctx context.Context, w *consumer) {
+	go w.audit(1)
+	go func() {
+		<-ctx.Done()
+	}()
+	go println("started")
+}
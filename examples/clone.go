@@ -0,0 +1,18 @@
+package main
+
+type Settings struct {
+	Tags []string
+}
+
+func (s *Settings) Clone() *Settings {
+	c := *s
+	c.Tags = append([]string(nil), s.Tags...)
+	return &c
+}
+
+func publishSettings(updates chan *Settings, s *Settings) {
+	updates <- s.Clone()
+	snapshot := s.Clone()
+	updates <- snapshot
+	updates <- s
+}
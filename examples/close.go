@@ -0,0 +1,82 @@
+package main
+
+import "sync"
+
+type stopper struct {
+	done     chan struct{}
+	once     sync.Once
+	finished chan struct{}
+}
+
+func (s *stopper) Stop() {
+	close(s.done)
+}
+
+func (s *stopper) fail() {
+	close(s.done)
+}
+
+func (s *stopper) Finish() {
+	s.once.Do(func() { close(s.finished) })
+}
+
+func closeIt(ch chan int) {
+	close(ch)
+}
+
+func closes(values []int, failed bool) {
+	out := make(chan int)
+	if failed {
+		close(out)
+		return
+	}
+	close(out)
+
+	errs := make(chan error)
+	if failed {
+		close(errs)
+	} else {
+		close(errs)
+	}
+
+	results := make(chan int)
+	for range values {
+		close(results)
+	}
+
+	twice := make(chan int)
+	closeIt(twice)
+	defer close(twice)
+}
+
+func unclosed(n int) int {
+	jobs := make(chan int)
+	go func() {
+		for i := range n {
+			jobs <- i
+		}
+	}()
+	total := 0
+	for j := range jobs {
+		total += j
+	}
+	return total
+}
+
+func sentinel(n int) int {
+	jobs := make(chan int)
+	go func() {
+		for i := range n {
+			jobs <- i
+		}
+		jobs <- -1
+	}()
+	total := 0
+	for j := range jobs {
+		if j < 0 {
+			break
+		}
+		total += j
+	}
+	return total
+}
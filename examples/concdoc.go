@@ -0,0 +1,30 @@
+package main
+
+import "sync"
+
+// Tally is safe for concurrent use by multiple goroutines.
+type Tally struct {
+	n int
+}
+
+func (t *Tally) Add() {
+	t.n++
+}
+
+// Ledger is safe for concurrent use by multiple goroutines.
+type Ledger struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (l *Ledger) Record(entry string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry)
+}
+
+// Index maps names to positions.
+type Index struct {
+	mu sync.RWMutex
+	m  map[string]int
+}
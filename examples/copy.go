@@ -0,0 +1,19 @@
+package main
+
+type point struct {
+	x, y int
+}
+
+type tracker struct {
+	updates chan *point
+}
+
+func track(t *tracker, p *point) {
+	t.updates <- p
+	origin := point{}
+	t.updates <- &origin
+}
+
+func newTracker() *tracker {
+	return &tracker{updates: make(chan *point, 1)}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"os"
+)
+
+func listenAndServe() error { return nil }
+
+func serveOrExit() {
+	if err := listenAndServe(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func startServers() {
+	go func() {
+		if err := listenAndServe(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+	go serveOrExit()
+	go func() {
+		serveOrExit()
+	}()
+	go os.Exit(1)
+}
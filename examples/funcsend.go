@@ -0,0 +1,18 @@
+package main
+
+type handler struct {
+	served int
+}
+
+func (h *handler) handle(req *Task) { h.served++ }
+
+func report() {}
+
+func schedule(work chan func(), h *handler, req *Task, id int) {
+	work <- func() { h.handle(req) }
+	work <- func() { println(id) }
+	work <- report
+	work <- h.Run
+}
+
+func (h *handler) Run() {}
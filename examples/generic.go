@@ -0,0 +1,21 @@
+package main
+
+type number interface {
+	~int | ~int64 | ~float64
+}
+
+func sendNumber[T number](ch chan T, v T) {
+	ch <- v
+}
+
+func sendAny[T any](ch chan T, v T) {
+	ch <- v
+}
+
+type box[T any] struct {
+	value T
+}
+
+func sendBox(ch chan box[*int], v *int) {
+	ch <- box[*int]{value: v}
+}
@@ -0,0 +1,45 @@
+package main
+
+import "sync"
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]int{} //tsgo:guardedby registryMu
+)
+
+type Stats struct {
+	mu   sync.Mutex
+	hits int //tsgo:guardedby mu
+	sync.RWMutex
+	// misses counts lookups that found nothing.
+	//
+	//tsgo:guardedby RWMutex
+	misses int
+}
+
+func (s *Stats) Hit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hits++
+}
+
+func (s *Stats) Hits() int {
+	return s.hits
+}
+
+func (s *Stats) Miss() {
+	s.RLock()
+	s.misses++
+	s.RUnlock()
+}
+
+func (s *Stats) resetLocked() {
+	s.hits = 0
+}
+
+func lookup(name string) int {
+	registryMu.RLock()
+	n := registry[name]
+	registryMu.RUnlock()
+	return n + registry[name]
+}
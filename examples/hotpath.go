@@ -0,0 +1,20 @@
+//tsgo:ignorefile global-var -- lookup tables are written once, at init
+
+package main
+
+var table = [4]int{1, 2, 3, 4}
+
+// fanOut hands the same buffer to every worker; they only read it.
+//
+//tsgo:ignore go-pointer-arg -- the workers never write to buf
+func fanOut(buf []byte, workers int) {
+	for i := 0; i < workers; i++ {
+		go process(buf)
+	}
+}
+
+func process(buf []byte) {}
+
+func unrelated(ch chan *int) {
+	ch <- &table[0]
+}
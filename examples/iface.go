@@ -0,0 +1,19 @@
+package main
+
+func publish(events chan any, state any) {
+	events <- state
+}
+
+type State struct {
+	Name string
+}
+
+func publishDynamic(events chan any, s *State) {
+	var count any = 1
+	events <- count
+	var current any
+	current = s
+	events <- current
+	var name any = s.Name
+	go func(any) {}(name)
+}
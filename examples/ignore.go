@@ -0,0 +1,9 @@
+package main
+
+func handOff(ch chan *int, v *int) {
+	ch <- v //tsgo:ignore chan-pointer-send -- the receiver owns v from here on
+	//tsgo:ignore TS0001
+	ch <- v
+	//tsgo:ignore chan-pointer-sned -- typo
+	ch <- v
+}
@@ -0,0 +1,10 @@
+package main
+
+var cache map[string]string
+
+func refreshCache() {}
+
+func init() {
+	cache = map[string]string{}
+	go refreshCache()
+}
@@ -0,0 +1,62 @@
+package main
+
+import "sync"
+
+func store(id int) error { return nil }
+
+func saveAndForget(id int) {
+	go store(id)
+}
+
+func saveAndWait(id int) error {
+	done := make(chan error)
+	go func() { done <- store(id) }()
+	return <-done
+}
+
+func saveAll(ids []int) {
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store(id)
+		}()
+	}
+	wg.Wait()
+}
+
+func generate(n int) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for i := range n {
+			out <- i
+		}
+	}()
+	return out
+}
+
+type storePool struct {
+	wg  sync.WaitGroup
+	ids chan int
+}
+
+func newStorePool() *storePool {
+	p := &storePool{ids: make(chan int)}
+	p.wg.Add(1)
+	go p.worker()
+	return p
+}
+
+func (p *storePool) worker() {
+	defer p.wg.Done()
+	for id := range p.ids {
+		store(id)
+	}
+}
+
+func (p *storePool) Close() {
+	close(p.ids)
+	p.wg.Wait()
+}
@@ -0,0 +1,69 @@
+package main
+
+import "time"
+
+func waitForMessages(msgs <-chan string) {
+	for {
+		select {
+		case msg := <-msgs:
+			println(msg)
+		case <-time.After(time.Minute):
+			return
+		}
+	}
+}
+
+func refresh() {}
+
+func pollLeaky(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			refresh()
+		case <-done:
+			return
+		}
+	}
+}
+
+func pollStopped(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			refresh()
+		case <-done:
+			return
+		}
+	}
+}
+
+func pollStoppedOnReturn(done <-chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			refresh()
+		case <-done:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+func pollEarlyReturn(done <-chan struct{}, skip bool) {
+	ticker := time.NewTicker(time.Second)
+	if skip {
+		return
+	}
+	<-ticker.C
+	ticker.Stop()
+}
+
+func tickForever() {
+	for range time.Tick(time.Second) {
+		refresh()
+	}
+}
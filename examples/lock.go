@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+var errNoSession = errors.New("no such session")
+
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]string
+}
+
+func (s *sessionStore) user(id string) (string, error) {
+	s.mu.Lock()
+	user, ok := s.sessions[id]
+	if !ok {
+		return "", errNoSession
+	}
+	s.mu.Unlock()
+	return user, nil
+}
+
+func (s *sessionStore) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[id]; !ok {
+		return
+	}
+	delete(s.sessions, id)
+}
+
+func (s *sessionStore) users(ids []string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var users []string
+	for _, id := range ids {
+		if user, err := s.user(id); err == nil {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+type hitCounter struct {
+	mu   sync.RWMutex
+	hits map[string]int
+}
+
+func (h *hitCounter) touch(key string) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	h.hits[key]++
+}
+
+func (h *hitCounter) count(key string) int {
+	h.mu.RLock()
+	defer h.mu.Unlock()
+	return h.hits[key]
+}
+
+type eventQueue struct {
+	mu      sync.Mutex
+	pending int
+	events  chan string
+}
+
+func (q *eventQueue) publish(ev string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending++
+	q.events <- ev
+}
+
+type pageCache struct {
+	mu    sync.Mutex
+	url   string
+	pages map[string][]byte
+}
+
+func (c *pageCache) refresh(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp, err := http.Get(c.url + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	page, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	c.pages[path] = page
+	return nil
+}
+
+type inventory struct {
+	mu     sync.Mutex
+	stock  map[string]int
+	orders *orderBook
+}
+
+type orderBook struct {
+	mu     sync.Mutex
+	placed []string
+}
+
+func (inv *inventory) reserve(item string) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+	inv.stock[item]--
+	inv.orders.mu.Lock()
+	inv.orders.placed = append(inv.orders.placed, item)
+	inv.orders.mu.Unlock()
+}
+
+func (o *orderBook) cancel(inv *inventory, item string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	inv.mu.Lock()
+	inv.stock[item]++
+	inv.mu.Unlock()
+}
@@ -0,0 +1,20 @@
+package main
+
+import "sync"
+
+type nameRegistry struct {
+	state struct {
+		mu    sync.Mutex
+		names []string
+	}
+}
+
+func newRegistry() nameRegistry {
+	return nameRegistry{}
+}
+
+func (r *nameRegistry) add(name string) {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	r.state.names = append(r.state.names, name)
+}
@@ -0,0 +1,28 @@
+//go:build go1.21
+
+package main
+
+func startAll(jobs []func(), started chan<- int) {
+	for i, job := range jobs {
+		go func() {
+			job()
+			println(i)
+		}()
+		started <- i
+	}
+}
+
+type task struct{ id int }
+
+func (t *task) run() {}
+
+func runTasks(tasks []task, done chan<- bool) {
+	for _, t := range tasks {
+		go t.run()
+		done <- true
+	}
+	for _, t := range tasks {
+		go println(&t)
+		done <- true
+	}
+}
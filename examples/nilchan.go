@@ -0,0 +1,30 @@
+package main
+
+type server struct {
+	quit chan struct{}
+}
+
+func newServer() *server {
+	return &server{quit: make(chan struct{})}
+}
+
+func (s *server) stop() {
+	close(s.quit)
+}
+
+func maybeMade(buffered bool, values []int) {
+	var out chan int
+	if buffered {
+		out = make(chan int, len(values))
+	}
+	out <- 1
+	var in chan int
+	in = make(chan int, 1)
+	in <- 1
+	var never chan int
+	select {
+	case v := <-never:
+		_ = v
+	default:
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"log"
+	"regexp"
+)
+
+func mustMatch(pattern, s string) bool {
+	return regexp.MustCompile(pattern).MatchString(s)
+}
+
+func recoverWorker() {
+	if r := recover(); r != nil {
+		log.Print(r)
+	}
+}
+
+func matchWorker(patterns <-chan string, matches chan<- bool) {
+	for pattern := range patterns {
+		matches <- mustMatch(pattern, "input")
+	}
+}
+
+func guardedWorker(values <-chan any) {
+	defer recoverWorker()
+	for v := range values {
+		_ = v.(string)
+	}
+}
+
+func startMatchers(patterns <-chan string, matches chan<- bool, values <-chan any) {
+	go matchWorker(patterns, matches)
+	go guardedWorker(values)
+	go func() {
+		for v := range values {
+			if s, ok := v.(string); ok {
+				log.Print(s)
+			}
+			switch v.(type) {
+			case int:
+			}
+			log.Print(v.(error))
+		}
+	}()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Print(r)
+			}
+		}()
+		panic("recovered")
+	}()
+	go mustMatch("a", "b")
+}
@@ -0,0 +1,8 @@
+package main
+
+import "github.com/rpetrich/tsgo/examples/pool"
+
+func spawnCounter() {
+	counter := 0
+	pool.Spawn(&counter)
+}
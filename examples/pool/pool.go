@@ -0,0 +1,9 @@
+// Package pool is a dependency of the examples whose facts, that Spawn hands
+// its argument to a goroutine, must reach them when only the examples are
+// analyzed.
+package pool
+
+func use(p *int) { *p++ }
+
+// Spawn increments *p on another goroutine.
+func Spawn(p *int) { go use(p) }
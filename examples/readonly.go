@@ -0,0 +1,15 @@
+package main
+
+import "fmt"
+
+func trimTo(limit int) {}
+
+func trimInBackground(limit int) {
+	go func() {
+		trimTo(limit)
+	}()
+	fmt.Println("trimming to", limit)
+	go func(limit int) {
+		trimTo(limit)
+	}(limit)
+}
@@ -0,0 +1,35 @@
+package main
+
+type Task struct {
+	ID   int
+	Data []byte
+}
+
+func (t *Task) Run() {}
+
+func consume(tasks <-chan *Task, results <-chan Task) {
+	for task := range tasks {
+		task.Run()
+	}
+	task := <-tasks
+	task.Run()
+	<-tasks
+	r := <-results
+	r.Run()
+}
+
+func poll(tasks <-chan *Task, done <-chan struct{}, out chan<- *Task) {
+	for {
+		select {
+		case task, ok := <-tasks:
+			if !ok {
+				return
+			}
+			out <- task
+		case out <- &Task{}:
+		case <-tasks:
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "sync"
+
+type pipeline struct {
+	values chan int
+	cancel func()
+}
+
+func (p *pipeline) produce() {
+	for i := range 10 {
+		p.values <- i
+	}
+}
+
+func (p *pipeline) consume() {
+	for v := range p.values {
+		if v < 0 {
+			close(p.values)
+		}
+	}
+}
+
+// The goroutines collectSquares starts send its results, so it may close the
+// channel once they are done.
+func collectSquares(nums []int) []int {
+	results := make(chan int, len(nums))
+	var wg sync.WaitGroup
+	wg.Add(len(nums))
+	for _, n := range nums {
+		go func() {
+			defer wg.Done()
+			results <- n * n
+		}()
+	}
+	wg.Wait()
+	close(results)
+	var squares []int
+	for r := range results {
+		squares = append(squares, r)
+	}
+	return squares
+}
+
+func (p *pipeline) drainAndClose(producers *sync.WaitGroup) {
+	for len(p.values) > 0 {
+		<-p.values
+	}
+	producers.Wait()
+	close(p.values)
+}
@@ -0,0 +1,12 @@
+package main
+
+import "errors"
+
+func flush() error { return errors.New("disk full") }
+
+func flushInBackground() {
+	go flush()
+	go func() {
+		_ = flush()
+	}()
+}
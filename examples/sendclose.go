@@ -0,0 +1,53 @@
+package main
+
+import "sync"
+
+func compute() int { return 0 }
+
+func racyClose() {
+	results := make(chan int)
+	go func() {
+		results <- compute()
+	}()
+	close(results)
+}
+
+func waitedClose() {
+	results := make(chan int, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		results <- compute()
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	for r := range results {
+		_ = r
+	}
+}
+
+func producerCloses() <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		out <- compute()
+	}()
+	return out
+}
+
+func deferredClose(n int) {
+	results := make(chan int, n)
+	defer close(results)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- compute()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+)
+
+type signalWaiter struct {
+	sigs chan os.Signal
+}
+
+func newSignalWaiter() *signalWaiter {
+	w := &signalWaiter{sigs: make(chan os.Signal)}
+	signal.Notify(w.sigs, os.Interrupt)
+	return w
+}
+
+func waitForInterrupt() {
+	buffered := make(chan os.Signal, 1)
+	signal.Notify(buffered, os.Interrupt)
+	log.Print(<-buffered)
+
+	signal.Notify(make(chan os.Signal, 0), os.Kill)
+}
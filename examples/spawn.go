@@ -0,0 +1,35 @@
+package main
+
+import "sync"
+
+func fetch(url string) error { return nil }
+
+func fetchAll(urls []string, conns <-chan string) {
+	for url := range conns {
+		go fetch(url)
+	}
+	sem := make(chan struct{}, 8)
+	for url := range conns {
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem }()
+			fetch(url)
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		go func() {
+			for url := range conns {
+				fetch(url)
+			}
+		}()
+	}
+	var wg sync.WaitGroup
+	wg.Add(len(urls))
+	for _, url := range urls {
+		go func() {
+			defer wg.Done()
+			fetch(url)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,16 @@
+package main
+
+import "sync"
+
+type counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func sendSync(counters chan counter, groups chan sync.WaitGroup, shared chan *counter) {
+	var c counter
+	counters <- c
+	var wg sync.WaitGroup
+	groups <- wg
+	shared <- &c
+}
@@ -0,0 +1,16 @@
+package main
+
+type Request struct {
+	Config *Job `tsgo:"immutable"`
+	Body   []byte
+}
+
+type Lookup struct {
+	Table map[string]int `tsgo:"shared"`
+	Key   string
+}
+
+func serve(requests chan Request, lookups chan Lookup) {
+	requests <- Request{}
+	lookups <- Lookup{}
+}
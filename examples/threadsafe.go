@@ -0,0 +1,21 @@
+package main
+
+import "sync"
+
+// Counter is safe for concurrent use.
+//
+//tsgo:threadsafe
+type Counter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *Counter) Add() {
+	c.mu.Lock()
+	c.n++
+	c.mu.Unlock()
+}
+
+func count(counters chan *Counter) {
+	counters <- &Counter{}
+}
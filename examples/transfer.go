@@ -0,0 +1,32 @@
+package main
+
+type Job struct {
+	ID   int
+	Done bool
+}
+
+//tsgo:owns job
+func run(job *Job) {
+	job.Done = true
+}
+
+func dispatch(n int) {
+	jobs := make(chan *Job, n) //tsgo:transfers
+	go func() {
+		for job := range jobs {
+			run(job)
+		}
+	}()
+	for i := 0; i < n; i++ {
+		job := &Job{ID: i}
+		jobs <- job
+	}
+	last := &Job{ID: n}
+	jobs <- last
+	last.ID++
+	spare := &Job{}
+	for i := 0; i < n; i++ {
+		spare.ID = i
+		go run(spare)
+	}
+}
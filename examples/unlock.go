@@ -0,0 +1,38 @@
+package main
+
+import (
+	"regexp"
+	"sync"
+)
+
+type patternCache struct {
+	mu       sync.RWMutex
+	patterns map[string]string
+	compiled map[string]*regexp.Regexp
+}
+
+func (c *patternCache) tryGet(key string) (*regexp.Regexp, bool) {
+	c.mu.Lock()
+	re, ok := c.compiled[key]
+	if !ok {
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.mu.Unlock()
+	return re, true
+}
+
+func (c *patternCache) pattern(key string) string {
+	c.mu.RLock()
+	p := c.patterns[key]
+	c.mu.RUnlock()
+	return p
+}
+
+func (c *patternCache) compile(key string) *regexp.Regexp {
+	c.mu.Lock()
+	re := regexp.MustCompile(c.patterns[key])
+	c.compiled[key] = re
+	c.mu.Unlock()
+	return re
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"math/rand"
+	"text/template"
+)
+
+func render(buf *bytes.Buffer) {}
+
+func audit(tx *sql.Tx) {}
+
+func shuffle(r *rand.Rand) {}
+
+func unsafeTypes(db *sql.DB, header string) {
+	var buf bytes.Buffer
+	go render(&buf)
+	go func() {
+		buf.WriteString("header")
+	}()
+	tx, _ := db.Begin()
+	go audit(tx)
+	r := rand.New(rand.NewSource(1))
+	go shuffle(r)
+	t := template.New("page")
+	go t.Parse(header)
+}
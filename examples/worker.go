@@ -0,0 +1,5 @@
+package main
+
+func worker(ch chan *int) {
+	ch <- &foo
+}
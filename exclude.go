@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// stringList is a flag.Value accumulating comma-separated values across
+// repeated uses of a flag.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// pathFilter decides which files are analyzed. Paths are matched relative to
+// the working directory with forward slashes; an exclude pattern matches a
+// file if it matches the whole path, any leading directory of it, or its base
+// name, so -exclude=internal/gen and -exclude='*_mock.go' both do what one
+// expects. Patterns from a configuration file are matched relative to the
+// directory holding it instead.
+type pathFilter struct {
+	root     string
+	excludes []exclusion
+}
+
+type exclusion struct {
+	base, pattern string
+}
+
+func newPathFilter(excludes []string) (*pathFilter, error) {
+	root, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	f := &pathFilter{root: root}
+	return f, f.addExcludes(root, excludes)
+}
+
+// addExcludes adds patterns matched relative to the directory base.
+func (f *pathFilter) addExcludes(base string, patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %v", pattern, err)
+		}
+		f.excludes = append(f.excludes, exclusion{base: base, pattern: pattern})
+	}
+	return nil
+}
+
+func (f *pathFilter) relative(filename string) string {
+	return relativeTo(f.root, filename)
+}
+
+func relativeTo(base, filename string) string {
+	rel, err := filepath.Rel(base, filename)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(filename)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (f *pathFilter) excluded(filename string) bool {
+	rel := f.relative(filename)
+	elems := strings.Split(rel, "/")
+	for _, elem := range elems[:len(elems)-1] {
+		if skippedDirs[elem] {
+			return true
+		}
+	}
+	for _, e := range f.excludes {
+		if ok, _ := path.Match(e.pattern, path.Base(rel)); ok {
+			return true
+		}
+		elems := elems
+		if e.base != f.root {
+			elems = strings.Split(relativeTo(e.base, filename), "/")
+		}
+		for i := range elems {
+			if ok, _ := path.Match(e.pattern, strings.Join(elems[:i+1], "/")); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rpetrich/tsgo/analyzer"
+)
+
+// explainMain implements tsgo explain, printing the documentation of the
+// checks named by args, by ID or name. It does not return.
+func explainMain(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: tsgo explain ID-or-name...")
+		os.Exit(2)
+	}
+	status := 0
+	for i, arg := range args {
+		check := analyzer.Lookup(arg)
+		if check == nil {
+			fmt.Fprintf(os.Stderr, "tsgo: unknown check %q\n", arg)
+			status = 2
+			continue
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		explain(os.Stdout, check)
+	}
+	os.Exit(status)
+}
+
+func explain(w io.Writer, check *analyzer.Check) {
+	fmt.Fprintf(w, "%s: %s\n", checkLabel(check.ID, check.Name), check.Doc)
+	if check.Rationale != "" {
+		fmt.Fprintf(w, "\n%s\n", check.Rationale)
+	}
+	for _, example := range []struct{ title, code string }{
+		{"Bad", check.Bad},
+		{"Recommended", check.Good},
+	} {
+		if example.code == "" {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n\n", example.title)
+		for _, line := range strings.Split(example.code, "\n") {
+			if line == "" {
+				fmt.Fprintln(w)
+			} else {
+				fmt.Fprintf(w, "\t%s\n", line)
+			}
+		}
+	}
+}
+
+// checkLabel identifies a check in output, as "TS0001 chan-pointer-send" or
+// by name alone for checks without an ID.
+func checkLabel(id, name string) string {
+	if id == "" {
+		return name
+	}
+	return id + " " + name
+}
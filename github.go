@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rpetrich/tsgo/analyzer"
+)
+
+// writeGitHub writes the report as GitHub Actions workflow commands, which the
+// runner turns into annotations shown inline on pull requests.
+func writeGitHub(w io.Writer, r *report) error {
+	for _, n := range r.Notes {
+		if _, err := fmt.Fprintf(w, "::notice title=tsgo::%s\n", escapeGitHubData(n.Pos+": "+n.Message)); err != nil {
+			return err
+		}
+	}
+	for _, d := range r.Diagnostics {
+		properties := []string{
+			"file=" + r.Relative(d.Pos.Filename),
+			fmt.Sprintf("line=%d", d.Pos.Line),
+			fmt.Sprintf("col=%d", d.Pos.Column),
+		}
+		if d.End.Line > 0 {
+			properties = append(properties, fmt.Sprintf("endLine=%d", d.End.Line))
+			if d.End.Line == d.Pos.Line {
+				properties = append(properties, fmt.Sprintf("endColumn=%d", d.End.Column))
+			}
+		}
+		properties = append(properties, "title="+checkLabel(d.ID, d.Check))
+		for i, p := range properties {
+			name, value, _ := strings.Cut(p, "=")
+			properties[i] = name + "=" + escapeGitHubProperty(value)
+		}
+		if _, err := fmt.Fprintf(w, "::%s %s::%s\n", gitHubLevel(d.Severity), strings.Join(properties, ","), escapeGitHubData(d.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gitHubLevel returns the workflow command for findings of a severity.
+func gitHubLevel(severity analyzer.Severity) string {
+	if severity == analyzer.SeverityInfo {
+		return "notice"
+	}
+	return string(severity)
+}
+
+// escapeGitHubData escapes the message of a workflow command as the runner
+// expects, so that multi-line messages survive.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value, which additionally must not
+// contain the separators of the property list.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
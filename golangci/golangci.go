@@ -0,0 +1,159 @@
+// Package golangci registers tsgo as a golangci-lint module plugin.
+//
+// To use it, list the module in .custom-gcl.yml:
+//
+//	version: v2.1.0
+//	plugins:
+//	  - module: github.com/rpetrich/tsgo
+//	    import: github.com/rpetrich/tsgo/golangci
+//	    version: latest
+//
+// build a custom binary with golangci-lint custom, and enable the linter in
+// .golangci.yml:
+//
+//	linters:
+//	  enable:
+//	    - tsgo
+//	  settings:
+//	    custom:
+//	      tsgo:
+//	        type: module
+//	        settings:
+//	          preset: strict
+//	          include:
+//	            - mixed-atomic
+//	          disable:
+//	            - global-var
+//	          severity:
+//	            unjoined-goroutine: warning
+//	          nested-severity: info
+//	          strict-suppressions: true
+//	          safe-types:
+//	            - "*go.uber.org/zap.Logger"
+//	            - "*github.com/acme/metrics.*"
+//	          safe-interfaces:
+//	            - github.com/acme/conc.ConcurrencySafe
+//	          sanitizers:
+//	            - google.golang.org/protobuf/proto.Clone
+//	          async-callbacks:
+//	            - github.com/acme/sched.Every
+//	          spawn-wrappers:
+//	            - (*github.com/acme/workers.Pool).Submit
+//	          blocking-funcs:
+//	            - (*github.com/acme/rpc.Client).Call
+//	          unsafe-types:
+//	            shared-buffer:
+//	              - "*github.com/acme/wire.Encoder"
+//	          min-critical-section: 2
+//
+// The preset, as with tsgo -preset, is the selection that enable, include and
+// disable adjust. golangci-lint gives every issue of a linter the same
+// severity, so when severity or nested-severity is set each message starts
+// with the severity of its finding, such as "info: ", for severity rules in
+// .golangci.yml to match on.
+package golangci
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golangci/plugin-module-register/register"
+	"github.com/rpetrich/tsgo/analyzer"
+	"golang.org/x/tools/go/analysis"
+)
+
+func init() {
+	register.Plugin("tsgo", New)
+}
+
+// Settings is the plugin configuration accepted under settings in
+// .golangci.yml.
+type Settings struct {
+	Preset             string                       `json:"preset"`
+	Enable             []string                     `json:"enable"`
+	Include            []string                     `json:"include"`
+	Disable            []string                     `json:"disable"`
+	Severity           map[string]analyzer.Severity `json:"severity"`
+	NestedSeverity     analyzer.Severity            `json:"nested-severity"`
+	StrictSuppressions bool                         `json:"strict-suppressions"`
+	SafeTypes          []string                     `json:"safe-types"`
+	SafeInterfaces     []string                     `json:"safe-interfaces"`
+	Sanitizers         []string                     `json:"sanitizers"`
+	AsyncCallbacks     []string                     `json:"async-callbacks"`
+	SpawnWrappers      []string                     `json:"spawn-wrappers"`
+	BlockingFuncs      []string                     `json:"blocking-funcs"`
+	UnsafeTypes        map[string][]string          `json:"unsafe-types"`
+	MinCriticalSection int                          `json:"min-critical-section"`
+}
+
+type plugin struct {
+	settings Settings
+}
+
+// New constructs the plugin from its golangci-lint settings.
+func New(settings any) (register.LinterPlugin, error) {
+	s, err := register.DecodeSettings[Settings](settings)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin{settings: s}, nil
+}
+
+func (p *plugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	name := p.settings.Preset
+	if name == "" {
+		name = "default"
+	}
+	preset, ok := analyzer.LookupPreset(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q (want %s)", name, strings.Join(analyzer.PresetNames(), ", "))
+	}
+	nestedSeverity := p.settings.NestedSeverity
+	if nestedSeverity == "" && len(p.settings.Enable) == 0 {
+		nestedSeverity = preset.NestedSeverity
+	}
+	a, err := analyzer.New(analyzer.Config{
+		Enable:             p.settings.Enable,
+		Include:            append(append([]string(nil), preset.Include...), p.settings.Include...),
+		Disable:            presetDisable(preset, p.settings),
+		SafeTypes:          p.settings.SafeTypes,
+		SafeInterfaces:     p.settings.SafeInterfaces,
+		Sanitizers:         p.settings.Sanitizers,
+		AsyncCallbacks:     p.settings.AsyncCallbacks,
+		SpawnWrappers:      p.settings.SpawnWrappers,
+		BlockingFuncs:      p.settings.BlockingFuncs,
+		UnsafeTypes:        p.settings.UnsafeTypes,
+		MinCriticalSection: p.settings.MinCriticalSection,
+		StrictSuppressions: p.settings.StrictSuppressions,
+		Severity:           p.settings.Severity,
+		NestedSeverity:     nestedSeverity,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []*analysis.Analyzer{a}, nil
+}
+
+// presetDisable returns the checks to disable: those preset disables, unless
+// settings enables or includes them, and those settings disables.
+func presetDisable(preset analyzer.Preset, settings Settings) []string {
+	enabled := map[string]bool{}
+	for _, key := range append(append([]string(nil), settings.Enable...), settings.Include...) {
+		for _, check := range analyzer.Resolve(key) {
+			enabled[check.Name] = true
+		}
+	}
+	var disable []string
+	for _, key := range preset.Disable {
+		for _, check := range analyzer.Resolve(key) {
+			if !enabled[check.Name] {
+				disable = append(disable, check.Name)
+			}
+		}
+	}
+	return append(disable, settings.Disable...)
+}
+
+func (p *plugin) GetLoadMode() string {
+	return register.LoadModeTypesInfo
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// reportMain implements tsgo report, which analyzes packages like tsgo itself
+// and writes the findings as a self-contained HTML page. It does not return.
+func reportMain(args []string) {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	var opts loadOptions
+	opts.register(flags)
+	output := flags.String("o", "tsgo-report.html", "file to write the report to")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: tsgo report [-o report.html] [flags] [packages | files]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if err := opts.validate(flags.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "tsgo: %v\n", err)
+		os.Exit(2)
+	}
+	r, err := analyze(&opts, flags.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tsgo: %v\n", err)
+		os.Exit(2)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		panic(err)
+	}
+	if err := writeHTML(f, r); err != nil {
+		panic(err)
+	}
+	if err := f.Close(); err != nil {
+		panic(err)
+	}
+	fmt.Fprintf(os.Stderr, "tsgo: wrote %d findings to %s\n", len(r.Diagnostics), *output)
+	os.Exit(0)
+}
+
+type htmlCount struct {
+	Name, ID, Severity string
+	Count              int
+}
+
+type htmlFinding struct {
+	Severity, Label, Location, Message string
+	// Excerpt holds the lines around the finding; the reported ones are
+	// marked.
+	Excerpt []htmlLine
+}
+
+type htmlLine struct {
+	Number int
+	Text   string
+	Marked bool
+}
+
+type htmlReport struct {
+	Packages, Files int
+	Findings        []htmlFinding
+	Checks          []htmlCount
+	ByPackage       []htmlCount
+	Notes           []note
+}
+
+// excerptContext is the number of lines shown on either side of a finding in
+// the HTML report.
+const excerptContext = 2
+
+// writeHTML writes the report as a single HTML page with no external
+// resources: a breakdown of the findings by check and by package, and every
+// finding with an excerpt of the surrounding source. The tables sort when a
+// column header is clicked.
+func writeHTML(w io.Writer, r *report) error {
+	out := htmlReport{Packages: r.Packages, Files: r.Files, Notes: r.Notes}
+	byCheck := map[string]int{}
+	byPackage := map[string]int{}
+	sources := map[string][]string{}
+	for _, d := range r.Diagnostics {
+		byCheck[d.Check]++
+		byPackage[d.Package]++
+		lines, ok := sources[d.Pos.Filename]
+		if !ok && r.ReadFile != nil {
+			if src, err := r.ReadFile(d.Pos.Filename); err == nil {
+				lines = strings.Split(string(src), "\n")
+			}
+			sources[d.Pos.Filename] = lines
+		}
+		finding := htmlFinding{
+			Severity: string(d.Severity),
+			Label:    checkLabel(d.ID, d.Check),
+			Location: fmt.Sprintf("%s:%d:%d", r.Relative(d.Pos.Filename), d.Pos.Line, d.Pos.Column),
+			Message:  d.String(),
+		}
+		end := max(d.End.Line, d.Pos.Line)
+		for n := max(1, d.Pos.Line-excerptContext); n <= min(len(lines), end+excerptContext); n++ {
+			finding.Excerpt = append(finding.Excerpt, htmlLine{
+				Number: n,
+				Text:   strings.TrimRight(lines[n-1], "\r"),
+				Marked: n >= d.Pos.Line && n <= end,
+			})
+		}
+		out.Findings = append(out.Findings, finding)
+	}
+	for _, check := range r.Checks {
+		out.Checks = append(out.Checks, htmlCount{Name: check.Name, ID: check.ID, Severity: string(check.DefaultSeverity()), Count: byCheck[check.Name]})
+	}
+	for pkg, count := range byPackage {
+		out.ByPackage = append(out.ByPackage, htmlCount{Name: pkg, Count: count})
+	}
+	sort.Slice(out.ByPackage, func(i, j int) bool { return out.ByPackage[i].Name < out.ByPackage[j].Name })
+	return htmlTemplate.Execute(w, out)
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>tsgo report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; cursor: pointer; user-select: none; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+td.num { text-align: right; }
+.error { color: #b00020; }
+.warning { color: #a05a00; }
+.info { color: #00639b; }
+pre { margin: 0.4em 0 0; background: #f8f8f8; padding: 0.4em; overflow-x: auto; tab-size: 4; }
+pre .marked { background: #fff3bf; }
+pre .num { color: #999; }
+</style>
+</head>
+<body>
+<h1>tsgo report</h1>
+<p>{{len .Findings}} findings in {{.Files}} files of {{.Packages}} packages.</p>
+{{- if .Notes}}
+<h2>Notes</h2>
+<ul>
+{{- range .Notes}}
+<li><code>{{.Pos}}</code>: {{.Message}}</li>
+{{- end}}
+</ul>
+{{- end}}
+<h2>By check</h2>
+<table class="sortable">
+<thead><tr><th>ID</th><th>Check</th><th>Severity</th><th>Findings</th></tr></thead>
+<tbody>
+{{- range .Checks}}
+<tr><td>{{.ID}}</td><td>{{.Name}}</td><td class="{{.Severity}}">{{.Severity}}</td><td class="num">{{.Count}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+<h2>By package</h2>
+<table class="sortable">
+<thead><tr><th>Package</th><th>Findings</th></tr></thead>
+<tbody>
+{{- range .ByPackage}}
+<tr><td>{{.Name}}</td><td class="num">{{.Count}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+<h2>Findings</h2>
+<table class="sortable">
+<thead><tr><th>Severity</th><th>Check</th><th>Location</th><th>Message</th></tr></thead>
+<tbody>
+{{- range .Findings}}
+<tr>
+<td class="{{.Severity}}">{{.Severity}}</td>
+<td>{{.Label}}</td>
+<td data-sort="{{.Location}}"><code>{{.Location}}</code></td>
+<td><details><summary>{{.Message}}</summary><pre>
+{{- range .Excerpt}}<span class="num">{{printf "%5d" .Number}}</span>  <span{{if .Marked}} class="marked"{{end}}>{{.Text}}</span>
+{{end -}}
+</pre></details></td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+<script>
+for (const table of document.querySelectorAll("table.sortable")) {
+  table.querySelectorAll("th").forEach((th, column) => {
+    th.addEventListener("click", () => {
+      const ascending = !th.classList.contains("asc");
+      table.querySelectorAll("th").forEach(other => other.classList.remove("asc", "desc"));
+      th.classList.add(ascending ? "asc" : "desc");
+      const body = table.tBodies[0];
+      const key = row => {
+        const cell = row.cells[column];
+        const text = cell.dataset.sort || cell.textContent.trim();
+        return cell.classList.contains("num") ? Number(text) : text;
+      };
+      const rows = Array.from(body.rows).sort((a, b) => {
+        const x = key(a), y = key(b);
+        const order = typeof x === "number" ? x - y : x.localeCompare(y, undefined, {numeric: true});
+        return ascending ? order : -order;
+      });
+      body.append(...rows);
+    });
+  });
+}
+</script>
+</body>
+</html>
+`))
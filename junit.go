@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the report as JUnit XML for CI systems that only display
+// test results: each check that ran is a test suite, and each finding a
+// failed test case named after its position. A check without findings gets a
+// single passing test case, so that it still shows up as having run.
+func writeJUnit(w io.Writer, r *report) error {
+	out := junitTestSuites{Name: "tsgo"}
+	suites := map[string]*junitTestSuite{}
+	for _, check := range r.Checks {
+		out.Suites = append(out.Suites, junitTestSuite{Name: checkLabel(check.ID, check.Name)})
+	}
+	for i := range out.Suites {
+		suites[r.Checks[i].Name] = &out.Suites[i]
+	}
+	for _, d := range r.Diagnostics {
+		suite := suites[d.Check]
+		if suite == nil {
+			continue
+		}
+		rel := r.Relative(d.Pos.Filename)
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      fmt.Sprintf("%s:%d:%d", rel, d.Pos.Line, d.Pos.Column),
+			ClassName: d.Check,
+			Failure: &junitFailure{
+				Message: d.String(),
+				Type:    string(d.Severity),
+				Text:    fmt.Sprintf("%s:%d:%d: %s\n%s", rel, d.Pos.Line, d.Pos.Column, d, d.Node),
+			},
+		})
+		suite.Failures++
+	}
+	for i := range out.Suites {
+		suite := &out.Suites[i]
+		if len(suite.TestCases) == 0 {
+			suite.TestCases = append(suite.TestCases, junitTestCase{Name: r.Checks[i].Name, ClassName: r.Checks[i].Name})
+		}
+		suite.Tests = len(suite.TestCases)
+		out.Tests += suite.Tests
+		out.Failures += suite.Failures
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/rpetrich/tsgo/analyzer"
+)
+
+// checkInfo is the JSON form of a check printed by tsgo list-checks -json.
+type checkInfo struct {
+	ID        string `json:"id,omitempty"`
+	Name      string `json:"name"`
+	Group     string `json:"group,omitempty"`
+	Severity  string `json:"severity"`
+	Default   bool   `json:"default"`
+	Doc       string `json:"doc"`
+	Rationale string `json:"rationale,omitempty"`
+	Bad       string `json:"bad,omitempty"`
+	Good      string `json:"good,omitempty"`
+}
+
+// listChecksMain implements tsgo list-checks, printing every registered check
+// as a table or, with -json, as a JSON array. It does not return.
+func listChecksMain(args []string) {
+	flags := flag.NewFlagSet("list-checks", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the checks as a JSON array, including their documentation")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: tsgo list-checks [-json]\n\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	checks := analyzer.Checks()
+	if *asJSON {
+		infos := make([]checkInfo, 0, len(checks))
+		for _, check := range checks {
+			infos = append(infos, checkInfo{
+				ID:        check.ID,
+				Name:      check.Name,
+				Group:     check.Group,
+				Severity:  string(check.DefaultSeverity()),
+				Default:   !check.OffByDefault,
+				Doc:       check.Doc,
+				Rationale: check.Rationale,
+				Bad:       check.Bad,
+				Good:      check.Good,
+			})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(infos); err != nil {
+			panic(err)
+		}
+		os.Exit(0)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tGROUP\tSEVERITY\tDESCRIPTION")
+	for _, check := range checks {
+		id, group := check.ID, check.Group
+		if id == "" {
+			id = "-"
+		}
+		if group == "" {
+			group = "-"
+		}
+		doc := check.Doc
+		if check.OffByDefault {
+			doc += " (off by default)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", id, check.Name, group, check.DefaultSeverity(), doc)
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+	os.Exit(0)
+}
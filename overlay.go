@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// readOverlay parses the file named by -overlay. Two forms are accepted: the
+// format used by go build -overlay, {"Replace": {"path": "replacement file"}},
+// and a plain map of file path to buffer contents as sent by editors. Paths
+// are made absolute, as go/packages requires.
+func readOverlay(path string) (map[string][]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing overlay %s: %v", path, err)
+	}
+	overlay := map[string][]byte{}
+	if replace, ok := raw["Replace"]; ok && len(raw) == 1 {
+		var files map[string]string
+		if err := json.Unmarshal(replace, &files); err != nil {
+			return nil, fmt.Errorf("parsing overlay %s: %v", path, err)
+		}
+		for name, replacement := range files {
+			contents, err := os.ReadFile(replacement)
+			if err != nil {
+				return nil, err
+			}
+			if err := addOverlay(overlay, name, contents); err != nil {
+				return nil, err
+			}
+		}
+		return overlay, nil
+	}
+	for name, value := range raw {
+		var contents string
+		if err := json.Unmarshal(value, &contents); err != nil {
+			return nil, fmt.Errorf("parsing overlay %s: contents of %s: %v", path, name, err)
+		}
+		if err := addOverlay(overlay, name, []byte(contents)); err != nil {
+			return nil, err
+		}
+	}
+	return overlay, nil
+}
+
+func addOverlay(overlay map[string][]byte, name string, contents []byte) error {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+	overlay[abs] = contents
+	return nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rpetrich/tsgo/analyzer"
+)
+
+// presetNames returns the names of the presets selectable with -preset or the
+// preset key of the configuration file, for usage messages.
+func presetNames() string {
+	return strings.Join(analyzer.PresetNames(), ", ")
+}
+
+// validatePreset returns an error unless name is empty or a known preset.
+func validatePreset(name string) error {
+	if _, ok := analyzer.LookupPreset(name); name != "" && !ok {
+		return fmt.Errorf("unknown preset %q (want %s)", name, presetNames())
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rpetrich/tsgo/analyzer"
+	"github.com/rpetrich/tsgo/checker"
+	"golang.org/x/tools/go/packages"
+)
+
+// A report is everything a run has to say: notes about problems that limited
+// the analysis, and the diagnostics found in the files being reported on.
+type report struct {
+	// Checks are the checks that ran.
+	Checks      []*analyzer.Check
+	Notes       []note
+	Diagnostics []checker.Diagnostic
+	// Packages, Files and Functions count what was analyzed and reported on,
+	// and Elapsed is the time it took.
+	Packages, Files, Functions int
+	Elapsed                    time.Duration
+	// Baselined is the number of findings suppressed by -baseline.
+	Baselined int
+	// Root is the working directory, and Relative returns the slash-separated
+	// path of a file relative to it, or the absolute path of files outside.
+	Root     string
+	Relative func(filename string) string
+	// ReadFile returns the contents of a file as it was analyzed.
+	ReadFile func(filename string) ([]byte, error)
+	// Color and Snippets control the decoration of text output.
+	Color, Snippets bool
+	// Template and SummaryTemplate are the templates of template output.
+	Template, SummaryTemplate *template.Template
+}
+
+// A note is a problem with the run itself, such as a type error, rather than
+// a finding in the code. Pos is "-" when there is no position.
+type note struct {
+	Pos     string
+	Message string
+}
+
+// loadNotes returns the errors encountered while loading and type-checking
+// pkgs. go/packages keeps type-checking past the first error, so the checks
+// still run against whatever type information was gathered. Errors inside
+// dependencies are not reported: their function bodies are trimmed during
+// loading, which the type checker complains about, and any genuine problem
+// with them surfaces as an import error in pkgs anyway.
+func loadNotes(pkgs []*packages.Package) []note {
+	var notes []note
+	for _, pkg := range pkgs {
+		for _, err := range pkg.Errors {
+			pos := err.Pos
+			if pos == "" {
+				pos = "-"
+			}
+			notes = append(notes, note{Pos: pos, Message: err.Msg})
+		}
+	}
+	return notes
+}
+
+// A formatter writes a report in one output format.
+type formatter func(w io.Writer, r *report) error
+
+var formatters = map[string]formatter{
+	"text":       writeText,
+	"json":       writeJSON,
+	"sarif":      writeSARIF,
+	"checkstyle": writeCheckstyle,
+	"junit":      writeJUnit,
+	"github":     writeGitHub,
+	"template":   writeTemplate,
+	"html":       writeHTML,
+}
+
+func formatNames() string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+type jsonReport struct {
+	Diagnostics []jsonDiagnostic `json:"diagnostics"`
+	Notes       []jsonNote       `json:"notes"`
+	Summary     jsonSummary      `json:"summary"`
+}
+
+type jsonDiagnostic struct {
+	File      string        `json:"file"`
+	Line      int           `json:"line"`
+	Column    int           `json:"column"`
+	EndLine   int           `json:"endLine"`
+	EndColumn int           `json:"endColumn"`
+	ID        string        `json:"id,omitempty"`
+	Check     string        `json:"check"`
+	Severity  string        `json:"severity"`
+	Message   string        `json:"message"`
+	Type      string        `json:"type,omitempty"`
+	Node      string        `json:"node"`
+	Related   []jsonRelated `json:"related,omitempty"`
+}
+
+type jsonRelated struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func newJSONDiagnostic(d checker.Diagnostic) jsonDiagnostic {
+	jd := jsonDiagnostic{
+		File:      d.Pos.Filename,
+		Line:      d.Pos.Line,
+		Column:    d.Pos.Column,
+		EndLine:   d.End.Line,
+		EndColumn: d.End.Column,
+		ID:        d.ID,
+		Check:     d.Check,
+		Severity:  string(d.Severity),
+		Message:   d.Message,
+		Node:      d.Node,
+	}
+	if d.Type != nil {
+		jd.Type = d.Type.String()
+	}
+	for _, r := range d.Related {
+		jd.Related = append(jd.Related, jsonRelated{File: r.Pos.Filename, Line: r.Pos.Line, Column: r.Pos.Column, Message: r.Message})
+	}
+	return jd
+}
+
+type jsonNote struct {
+	Pos     string `json:"pos"`
+	Message string `json:"message"`
+}
+
+type jsonSummary struct {
+	Diagnostics int            `json:"diagnostics"`
+	BySeverity  map[string]int `json:"bySeverity"`
+	Packages    int            `json:"packages"`
+	Files       int            `json:"files"`
+}
+
+// writeJSON writes the report as a single JSON object holding the
+// diagnostics, the notes and a summary of the run.
+func writeJSON(w io.Writer, r *report) error {
+	out := jsonReport{
+		Diagnostics: []jsonDiagnostic{},
+		Notes:       []jsonNote{},
+		Summary: jsonSummary{
+			Diagnostics: len(r.Diagnostics),
+			BySeverity:  map[string]int{},
+			Packages:    r.Packages,
+			Files:       r.Files,
+		},
+	}
+	for _, d := range r.Diagnostics {
+		out.Diagnostics = append(out.Diagnostics, newJSONDiagnostic(d))
+		out.Summary.BySeverity[string(d.Severity)]++
+	}
+	for _, n := range r.Notes {
+		out.Notes = append(out.Notes, jsonNote{Pos: n.Pos, Message: n.Message})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(out)
+}
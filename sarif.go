@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/rpetrich/tsgo/analyzer"
+)
+
+// The SARIF 2.1.0 subset written by writeSARIF. Field names follow the
+// specification, which consumers such as GitHub code scanning validate.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifact `json:"originalUriBaseIds,omitempty"`
+	Invocations        []sarifInvocation        `json:"invocations"`
+	Results            []sarifResult            `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifText          `json:"shortDescription"`
+	FullDescription      *sarifText         `json:"fullDescription,omitempty"`
+	Help                 *sarifHelp         `json:"help,omitempty"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifHelp struct {
+	Text     string `json:"text"`
+	Markdown string `json:"markdown"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string    `json:"level"`
+	Message sarifText `json:"message"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifText       `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+	// RelatedLocations are the places of checker.Diagnostic.Related.
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               int                   `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifText            `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           sarifRegion   `json:"region"`
+}
+
+type sarifArtifact struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// sarifLevel maps a severity to the SARIF level of the same meaning.
+func sarifLevel(severity analyzer.Severity) string {
+	if severity == analyzer.SeverityInfo {
+		return "note"
+	}
+	return string(severity)
+}
+
+// fileURI returns the file: URI of an absolute path.
+func fileURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// writeSARIF writes the report as a SARIF 2.1.0 log with a rule for every
+// registered check. Files under the working directory are given relative to
+// the %SRCROOT% base, which code scanning resolves to the repository root.
+func writeSARIF(w io.Writer, r *report) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "tsgo",
+			InformationURI: analyzer.Analyzer.URL,
+			Rules:          []sarifRule{},
+		}},
+		OriginalURIBaseIDs: map[string]sarifArtifact{
+			"%SRCROOT%": {URI: fileURI(r.Root) + "/"},
+		},
+		Invocations: []sarifInvocation{{ExecutionSuccessful: true}},
+		Results:     []sarifResult{},
+	}
+	ruleIndex := map[string]int{}
+	for _, check := range analyzer.Checks() {
+		rule := sarifRule{
+			ID:                   ruleID(check.ID, check.Name),
+			Name:                 check.Name,
+			ShortDescription:     sarifText{Text: check.Doc},
+			DefaultConfiguration: sarifConfiguration{Level: sarifLevel(check.DefaultSeverity())},
+		}
+		if check.Rationale != "" {
+			rule.FullDescription = &sarifText{Text: check.Rationale}
+			rule.Help = &sarifHelp{Text: check.Rationale, Markdown: helpMarkdown(check)}
+		}
+		ruleIndex[check.Name] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+	for _, n := range r.Notes {
+		message := n.Message
+		if n.Pos != "-" {
+			message = n.Pos + ": " + message
+		}
+		run.Invocations[0].ToolExecutionNotifications = append(run.Invocations[0].ToolExecutionNotifications, sarifNotification{
+			Level:   "warning",
+			Message: sarifText{Text: message},
+		})
+	}
+	artifactOf := func(filename string) sarifArtifact {
+		if rel := r.Relative(filename); !filepath.IsAbs(filepath.FromSlash(rel)) {
+			return sarifArtifact{URI: (&url.URL{Path: rel}).String(), URIBaseID: "%SRCROOT%"}
+		}
+		return sarifArtifact{URI: fileURI(filename)}
+	}
+	for _, d := range r.Diagnostics {
+		artifact := artifactOf(d.Pos.Filename)
+		var related []sarifLocation
+		for i, rel := range d.Related {
+			related = append(related, sarifLocation{
+				ID: i + 1,
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: artifactOf(rel.Pos.Filename),
+					Region:           sarifRegion{StartLine: rel.Pos.Line, StartColumn: rel.Pos.Column},
+				},
+				Message: &sarifText{Text: rel.Message},
+			})
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    ruleID(d.ID, d.Check),
+			RuleIndex: ruleIndex[d.Check],
+			Level:     sarifLevel(d.Severity),
+			Message:   sarifText{Text: d.String()},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: artifact,
+				Region: sarifRegion{
+					StartLine:   d.Pos.Line,
+					StartColumn: d.Pos.Column,
+					EndLine:     d.End.Line,
+					EndColumn:   d.End.Column,
+				},
+			}}},
+			RelatedLocations: related,
+		})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
+
+// ruleID identifies a check's SARIF rule by its stable ID, falling back to
+// its name for custom checks without one.
+func ruleID(id, name string) string {
+	if id == "" {
+		return name
+	}
+	return id
+}
+
+func helpMarkdown(check *analyzer.Check) string {
+	var b strings.Builder
+	b.WriteString(check.Rationale)
+	if check.Bad != "" {
+		b.WriteString("\n\nBad:\n\n```go\n" + check.Bad + "\n```")
+	}
+	if check.Good != "" {
+		b.WriteString("\n\nRecommended:\n\n```go\n" + check.Good + "\n```")
+	}
+	return b.String()
+}
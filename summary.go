@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// writeSummary writes counts of the findings by check and by package, the
+// amount of code analyzed and how long it took.
+func writeSummary(w io.Writer, r *report) error {
+	byCheck := map[string]int{}
+	byPackage := map[string]int{}
+	for _, d := range r.Diagnostics {
+		byCheck[d.Check]++
+		byPackage[d.Package]++
+	}
+	var b strings.Builder
+	b.WriteString("findings by check:\n")
+	for _, check := range r.Checks {
+		if n := byCheck[check.Name]; n > 0 {
+			fmt.Fprintf(&b, "%8d  %s\n", n, checkLabel(check.ID, check.Name))
+		}
+	}
+	b.WriteString("findings by package:\n")
+	packages := make([]string, 0, len(byPackage))
+	for pkg := range byPackage {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+	for _, pkg := range packages {
+		fmt.Fprintf(&b, "%8d  %s\n", byPackage[pkg], pkg)
+	}
+	fmt.Fprintf(&b, "%d findings in %d packages, %d files and %d functions, analyzed in %v\n",
+		len(r.Diagnostics), r.Packages, r.Files, r.Functions, r.Elapsed.Round(time.Millisecond))
+	if r.Baselined > 0 {
+		fmt.Fprintf(&b, "%d findings recorded in the baseline were not reported\n", r.Baselined)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
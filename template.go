@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// templateDiagnostic is the data a -template is executed with: the fields of
+// a diagnostic in JSON output, plus Path, the file name relative to the
+// working directory, and String, the message as printed in text output.
+type templateDiagnostic struct {
+	jsonDiagnostic
+	Path   string
+	String string
+}
+
+// templateSummary is the data a -template-summary is executed with.
+type templateSummary struct {
+	jsonSummary
+	Notes []jsonNote
+}
+
+// parseTemplate parses the template given to a flag, which is either the
+// template itself or, prefixed with @, the name of a file holding it. It
+// returns nil for an empty value.
+func parseTemplate(name, value string) (*template.Template, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if filename, ok := strings.CutPrefix(value, "@"); ok {
+		contents, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		value = string(contents)
+	}
+	return template.New(name).Parse(value)
+}
+
+// writeTemplate executes Template once per diagnostic, each followed by a
+// newline unless the template already ends with one, and then
+// SummaryTemplate, if any, once for the whole run.
+func writeTemplate(w io.Writer, r *report) error {
+	if r.Template == nil && r.SummaryTemplate == nil {
+		return fmt.Errorf("-format template requires -template or -template-summary")
+	}
+	summary := templateSummary{
+		jsonSummary: jsonSummary{
+			Diagnostics: len(r.Diagnostics),
+			BySeverity:  map[string]int{},
+			Packages:    r.Packages,
+			Files:       r.Files,
+		},
+		Notes: []jsonNote{},
+	}
+	for _, n := range r.Notes {
+		summary.Notes = append(summary.Notes, jsonNote{Pos: n.Pos, Message: n.Message})
+	}
+	for _, d := range r.Diagnostics {
+		summary.BySeverity[string(d.Severity)]++
+		if r.Template == nil {
+			continue
+		}
+		if err := executeLine(w, r.Template, templateDiagnostic{
+			jsonDiagnostic: newJSONDiagnostic(d),
+			Path:           r.Relative(d.Pos.Filename),
+			String:         d.String(),
+		}); err != nil {
+			return err
+		}
+	}
+	if r.SummaryTemplate != nil {
+		return executeLine(w, r.SummaryTemplate, summary)
+	}
+	return nil
+}
+
+func executeLine(w io.Writer, t *template.Template, data interface{}) error {
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return err
+	}
+	out := b.String()
+	if !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
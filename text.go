@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rpetrich/tsgo/analyzer"
+	"github.com/rpetrich/tsgo/checker"
+)
+
+// ANSI escape sequences used by colored text output.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+	ansiBlue   = "\x1b[34m"
+)
+
+// useColor decides whether text output is colored: "always", "never" or
+// "auto", which colors output to a terminal unless NO_COLOR is set.
+func useColor(mode string, f *os.File) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+			return false, nil
+		}
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("invalid -color %q (want auto, always or never)", mode)
+}
+
+// writeText writes one line per note and diagnostic in the style of compiler
+// errors, which editors and CI log viewers know how to link back to source.
+// With Snippets set, each diagnostic is followed by the offending source line
+// with the reported expression underlined. Related locations follow, one per
+// line.
+func writeText(w io.Writer, r *report) error {
+	paint := func(style, s string) string {
+		if !r.Color {
+			return s
+		}
+		return style + s + ansiReset
+	}
+	for _, n := range r.Notes {
+		if _, err := fmt.Fprintf(w, "%s:%s %s\n", paint(ansiBold, n.Pos), paint(ansiBlue, "note:"), n.Message); err != nil {
+			return err
+		}
+	}
+	sources := map[string][]byte{}
+	for _, d := range r.Diagnostics {
+		style := severityColor(d.Severity)
+		if _, err := fmt.Fprintf(w, "%s:%s %s %s\n", paint(ansiBold, d.Pos.String()), paint(style, string(d.Severity)+":"), d, paint(ansiBold, "["+checkLabel(d.ID, d.Check)+"]")); err != nil {
+			return err
+		}
+		if r.Snippets && r.ReadFile != nil {
+			src, ok := sources[d.Pos.Filename]
+			if !ok {
+				// A missing file merely loses its snippets.
+				src, _ = r.ReadFile(d.Pos.Filename)
+				sources[d.Pos.Filename] = src
+			}
+			if line, underline, ok := snippet(src, d); ok {
+				gutter := fmt.Sprintf("%5d | ", d.Pos.Line)
+				blank := strings.Repeat(" ", len(gutter)-2) + "| "
+				if _, err := fmt.Fprintf(w, "%s%s\n%s%s\n", paint(ansiBlue, gutter), line, paint(ansiBlue, blank), paint(style, underline)); err != nil {
+					return err
+				}
+			}
+		}
+		for _, related := range d.Related {
+			if _, err := fmt.Fprintf(w, "%s:%s %s\n", paint(ansiBold, related.Pos.String()), paint(ansiBlue, "related:"), related.Message); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func severityColor(severity analyzer.Severity) string {
+	switch severity {
+	case analyzer.SeverityError:
+		return ansiRed
+	case analyzer.SeverityInfo:
+		return ansiCyan
+	}
+	return ansiYellow
+}
+
+// snippet returns the source line on which d starts and a line of carets
+// under the reported range, stopping at the end of the line when the range
+// spans several. Tabs in the source are kept in the caret line so that the
+// carets line up however the terminal expands them.
+func snippet(src []byte, d checker.Diagnostic) (line, underline string, ok bool) {
+	if d.Pos.Offset < 0 || d.Pos.Offset > len(src) || d.Pos.Column < 1 {
+		return "", "", false
+	}
+	start := bytes.LastIndexByte(src[:d.Pos.Offset], '\n') + 1
+	end := bytes.IndexByte(src[start:], '\n')
+	if end < 0 {
+		end = len(src)
+	} else {
+		end += start
+	}
+	text := strings.TrimRight(string(src[start:end]), "\r")
+	from := d.Pos.Column - 1
+	if from > len(text) {
+		return "", "", false
+	}
+	to := len(text)
+	if d.End.Line == d.Pos.Line && d.End.Column-1 > from && d.End.Column-1 < to {
+		to = d.End.Column - 1
+	}
+	var b strings.Builder
+	for _, c := range text[:from] {
+		if c == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteString(strings.Repeat("^", max(1, utf8.RuneCountInString(text[from:to]))))
+	return text, b.String(), true
+}
@@ -2,161 +2,326 @@ package main
 
 import (
 	"bytes"
+	"flag"
 	"fmt"
 	"go/ast"
-	"go/build"
-	"go/importer"
 	"go/parser"
-	"go/printer"
 	"go/token"
-	"go/types"
-	"path/filepath"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/rpetrich/tsgo/analyzer"
+	"github.com/rpetrich/tsgo/checker"
+	"github.com/rpetrich/tsgo/internal/diff"
+	"golang.org/x/tools/go/packages"
 )
 
-func typeContainsPointer(t types.Type) (bool, types.Type) {
-	switch t := t.(type) {
-	case *types.Array:
-		return true, t
-	case *types.Basic:
-		if t.Kind() != types.UnsafePointer {
-			return true, t
-		}
-	case *types.Chan:
-		return false, nil
-	case *types.Interface:
-		return false, nil
-	case *types.Map:
-		return true, t
-	case *types.Named:
-		return typeContainsPointer(t.Underlying())
-	case *types.Pointer:
-		return true, t
-	case *types.Slice:
-		return true, t
-	case *types.Struct:
-		numFields := t.NumFields()
-		for i := 0; i < numFields; i++ {
-			fieldType := t.Field(i).Type()
-			if contains, subType := typeContainsPointer(fieldType); contains {
-				return true, subType
-			}
-		}
-		return false, nil
-	// case *types.Nil:
-	// 	return false
-	// case *types.Object:
-	// 	return true
-	}
-	return true, t
+// sourceFiles returns the parsed files of pkg that correspond to files written
+// by the user. For packages using cgo, go/packages parses the output of cgo
+// preprocessing; the translated files carry //line directives back to the
+// original source, while the support files cgo generates have no such mapping
+// and are dropped.
+func sourceFiles(pkg *packages.Package) []*ast.File {
+	goFiles := make(map[string]bool, len(pkg.GoFiles))
+	for _, path := range pkg.GoFiles {
+		goFiles[path] = true
+	}
+	files := make([]*ast.File, 0, len(pkg.Syntax))
+	for _, f := range pkg.Syntax {
+		if goFiles[pkg.Fset.Position(f.Package).Filename] {
+			files = append(files, f)
+		}
+	}
+	return files
 }
 
-func stringifyNode(fset *token.FileSet, node ast.Node) string {
-	buffer := bytes.Buffer{}
-	err := printer.Fprint(&buffer, fset, node)
-	if err != nil {
-		panic(err)
+// skippedDirs are never descended into: they hold third-party or fixture code
+// whose findings nobody analyzing the project can act on.
+var skippedDirs = map[string]bool{
+	"vendor":       true,
+	"testdata":     true,
+	"node_modules": true,
+}
+
+func isSkipped(pkg *packages.Package) bool {
+	for _, elem := range strings.Split(pkg.PkgPath, "/") {
+		if skippedDirs[elem] {
+			return true
+		}
 	}
-	return buffer.String()
+	return false
 }
 
-func printError(fset *token.FileSet, node ast.Node, message string, t types.Type) {
-	var annotation interface{}
-	if t != nil {
-		annotation = t
-	} else {
-		annotation = stringifyNode(fset, node)
-	}
-	fmt.Printf("%s:warning: %s (%v)\n", fset.Position(node.Pos()), message, annotation)
+// withoutTestDuplicates drops, from packages loaded with their tests, the
+// generated test main packages and each package that also has a variant
+// compiled with its tests, so that no file is analyzed twice.
+func withoutTestDuplicates(pkgs []*packages.Package) []*packages.Package {
+	tested := map[string]bool{}
+	for _, pkg := range pkgs {
+		if pkg.ID != pkg.PkgPath && !strings.HasSuffix(pkg.PkgPath, "_test") {
+			tested[pkg.PkgPath] = true
+		}
+	}
+	var kept []*packages.Package
+	for _, pkg := range pkgs {
+		if tested[pkg.ID] || pkg.Name == "main" && strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+		kept = append(kept, pkg)
+	}
+	return kept
+}
+
+// isGenerated reports whether f carries the standard "Code generated ... DO
+// NOT EDIT." marker. cgo stamps that marker on its translation of every file,
+// so for those the header of the original file is consulted instead.
+func isGenerated(fset *token.FileSet, f *ast.File) bool {
+	filename := fset.Position(f.Package).Filename
+	if fset.File(f.Package).Name() == filename {
+		return ast.IsGenerated(f)
+	}
+	original, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.PackageClauseOnly|parser.ParseComments)
+	return err == nil && ast.IsGenerated(original)
 }
 
-type visitor struct{
-	fset *token.FileSet
-	info types.Info
-	isInsideFunction bool
+func withoutGenerated(pkg *packages.Package, files []*ast.File) []*ast.File {
+	kept := files[:0]
+	for _, f := range files {
+		if !isGenerated(pkg.Fset, f) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
 }
 
-func (v *visitor) Visit(n ast.Node) ast.Visitor {
-	switch n := n.(type) {
-	case *ast.SendStmt:
-		contains, pointerType := typeContainsPointer(v.info.TypeOf(n.Value))
-		if contains {
-			printError(v.fset, n, "sending pointer type over a channel", pointerType)
-		}
-	case *ast.GoStmt:
-		contains, pointerType := typeContainsPointer(v.info.TypeOf(n.Call.Fun))
-		if contains {
-			printError(v.fset, n, "calling goroutine on a pointer type", pointerType)
-		}
-		for _, arg := range n.Call.Args {
-			contains, pointerType := typeContainsPointer(v.info.TypeOf(arg))
-			if contains {
-				printError(v.fset, arg, "calling goroutine with a pointer type", pointerType)
-			}
-		}
-	case *ast.GenDecl:
-		if !v.isInsideFunction && n.Tok == token.VAR {
-			for _, spec := range n.Specs {
-				printError(v.fset, spec, "global var declared", nil)
-			}
-		}
-	case *ast.FuncDecl:
-		newVisitor := *v
-		newVisitor.isInsideFunction = true
-		return &newVisitor
-	case *ast.Ident:
-		// obj := v.info.ObjectOf(n)
-		// switch obj := obj.(type) {
-		// case *types.Var:
-		// 	fmt.Printf("%s:warning: %s (%v)\n", v.fset.Position(n.Pos()), "name", obj.Name())
-		// }
-		// printError(v.fset, obj, "identifier", nil)
-		// printError(v.fset, n, "identifier", nil)
-	}
-	return v
+func excludeFiles(pkg *packages.Package, files []*ast.File, filter *pathFilter) []*ast.File {
+	kept := files[:0]
+	for _, f := range files {
+		if !filter.excluded(pkg.Fset.Position(f.Package).Filename) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
 }
 
 func main() {
-	pkg, err := build.ImportDir("./", 0)
+	if isVetInvocation(os.Args[1:]) {
+		vetMain()
+	}
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "explain":
+			explainMain(os.Args[2:])
+		case "list-checks":
+			listChecksMain(os.Args[2:])
+		case "report":
+			reportMain(os.Args[2:])
+		case "baseline":
+			baselineMain(os.Args[2:])
+		}
+	}
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "usage: tsgo [flags] [packages | files]\n")
+		fmt.Fprintf(out, "       tsgo explain ID-or-name...\n")
+		fmt.Fprintf(out, "       tsgo list-checks [-json]\n")
+		fmt.Fprintf(out, "       tsgo report [-o report.html] [flags] [packages | files]\n")
+		fmt.Fprintf(out, "       tsgo baseline write FILE [flags] [packages | files]\n\n")
+		fmt.Fprintf(out, "Packages are named by import path or pattern (./..., github.com/me/proj/...)\n")
+		fmt.Fprintf(out, "and resolved by the go command, as with go vet. Alternatively, a list of\n")
+		fmt.Fprintf(out, ".go files from a single directory is analyzed as one package.\n")
+		fmt.Fprintf(out, "With no arguments, the package in the current directory is analyzed.\n")
+		fmt.Fprintf(out, "Each finding ends with the ID and name of its check; tsgo explain ID\n")
+		fmt.Fprintf(out, "describes the check and how to fix what it reports.\n\n")
+		fmt.Fprintf(out, "The exit status is 1 if any finding has severity error, or with -strict-exit\n")
+		fmt.Fprintf(out, "if there are any findings at all, and 2 for usage errors. With -fail-over=N it\n")
+		fmt.Fprintf(out, "is 1 only if there are more than N findings.\n\n")
+		flag.PrintDefaults()
+	}
+	var opts loadOptions
+	opts.register(flag.CommandLine)
+	fix := flag.Bool("fix", false, "apply safe suggested fixes to the source files in place")
+	fixUnsafe := flag.Bool("fix-unsafe", false, "with -fix or -diff, also apply fixes that need review (implies -fix)")
+	showDiff := flag.Bool("diff", false, "print the suggested fixes as unified diffs instead of reporting findings; no files are changed")
+	format := flag.String("format", "text", "output format: "+formatNames())
+	color := flag.String("color", "auto", "color text output: auto (when writing to a terminal), always or never")
+	snippets := flag.Bool("snippets", true, "follow each finding in text output with the offending source line")
+	lineTemplate := flag.String("template", "", "with -format template, a text/template executed for each finding, or @file to read it from a file")
+	summaryTemplate := flag.String("template-summary", "", "with -format template, a text/template executed once after the findings, or @file")
+	summary := flag.Bool("summary", false, "after the findings, print counts by check and package and the time taken")
+	summaryOnly := flag.Bool("summary-only", false, "print the -summary counts in place of the findings")
+	output := flag.String("o", "", "write the findings to this file instead of standard output")
+	strictExit := flag.Bool("strict-exit", false, "exit with status 1 if there are findings of any severity, not only errors")
+	maxIssues := flag.Int("max-issues", 0, "report at most this many findings, noting how many were left out (0 for no limit)")
+	failOver := flag.Int("fail-over", -1, "exit with status 1 only if there are more than this many findings, of any severity")
+	flag.Parse()
+	if err := opts.validate(flag.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "tsgo: %v\n", err)
+		os.Exit(2)
+	}
+	if *fixUnsafe && !*showDiff {
+		*fix = true
+	}
+	if *fix && *showDiff {
+		fmt.Fprintln(os.Stderr, "tsgo: -fix and -diff are mutually exclusive")
+		os.Exit(2)
+	}
+	formatSet := false
+	flag.Visit(func(f *flag.Flag) { formatSet = formatSet || f.Name == "format" })
+	if !formatSet && opts.config.Format != "" {
+		*format = opts.config.Format
+	}
+	write, ok := formatters[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "tsgo: unknown -format %q (want one of %s)\n", *format, formatNames())
+		os.Exit(2)
+	}
+	var out io.Writer = os.Stdout
+	colorMode := *color
+	// report buffers the output of -o, which is only written once analysis
+	// succeeds, so that errors leave no empty or partial report behind.
+	var report *bytes.Buffer
+	if *output != "" {
+		if *showDiff {
+			fmt.Fprintln(os.Stderr, "tsgo: -o cannot be combined with -diff")
+			os.Exit(2)
+		}
+		report = &bytes.Buffer{}
+		out = report
+		if colorMode == "auto" {
+			colorMode = "never"
+		}
+	}
+	colored, err := useColor(colorMode, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tsgo: %v\n", err)
+		os.Exit(2)
+	}
+	lineTmpl, err := parseTemplate("template", *lineTemplate)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tsgo: %v\n", err)
+		os.Exit(2)
+	}
+	summaryTmpl, err := parseTemplate("template-summary", *summaryTemplate)
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "tsgo: %v\n", err)
+		os.Exit(2)
+	}
+	if *format == "template" && lineTmpl == nil && summaryTmpl == nil {
+		fmt.Fprintln(os.Stderr, "tsgo: -format template requires -template or -template-summary")
+		os.Exit(2)
+	}
+	if *fix && opts.stdin {
+		fmt.Fprintln(os.Stderr, "tsgo: -fix cannot be combined with -stdin")
+		os.Exit(2)
 	}
 
-	type file struct {
-		path string
-		node *ast.File
+	r, err := analyze(&opts, flag.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tsgo: %v\n", err)
+		os.Exit(2)
+	}
+	all := r.Diagnostics
+	total := len(all)
+	if *maxIssues > 0 && total > *maxIssues {
+		r.Diagnostics = r.Diagnostics[:*maxIssues]
+		r.Notes = append(r.Notes, note{Pos: "-", Message: fmt.Sprintf("%d more findings not shown (-max-issues=%d)", total-*maxIssues, *maxIssues)})
 	}
-	fset := token.NewFileSet()
-	files := make([]file, len(pkg.GoFiles))
-	for i, path := range pkg.GoFiles {
-		if pkg.Dir != "." {
-			path = filepath.Join(pkg.Dir, path)
+	r.Color, r.Snippets = colored, *snippets
+	r.Template, r.SummaryTemplate = lineTmpl, summaryTmpl
+	if *showDiff {
+		// Keep standard output a patch; the notes still matter.
+		for _, n := range r.Notes {
+			fmt.Fprintf(os.Stderr, "%s:note: %s\n", n.Pos, n.Message)
 		}
-		f, err := parser.ParseFile(fset, path, nil, 0)
-		if err != nil {
+	} else if !*summaryOnly {
+		if err := write(out, r); err != nil {
 			panic(err)
 		}
-		files[i] = file{
-			path: path,
-			node: f,
+	}
+	if *summary || *summaryOnly {
+		// Only text output has room for the summary alongside the findings.
+		summaryOut := out
+		if *format != "text" && !*summaryOnly || *showDiff {
+			summaryOut = os.Stderr
+		}
+		if err := writeSummary(summaryOut, r); err != nil {
+			panic(err)
 		}
 	}
 
-	cfg := types.Config{ Importer: importer.Default() }
+	if *fix || *showDiff {
+		fixed, applied, skipped, err := checker.ApplyFixes(r.Diagnostics, *fixUnsafe, r.ReadFile)
+		if err != nil {
+			panic(err)
+		}
+		if *showDiff {
+			printDiffs(fixed, r.ReadFile, r.Relative)
+		} else {
+			writeFixes(fixed, applied, skipped)
+		}
+	}
+	if report != nil {
+		if err := os.WriteFile(*output, report.Bytes(), 0o666); err != nil {
+			fmt.Fprintf(os.Stderr, "tsgo: %v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *failOver >= 0 {
+		if total > *failOver {
+			fmt.Fprintf(os.Stderr, "tsgo: %d findings exceed the budget of %d\n", total, *failOver)
+			os.Exit(1)
+		}
+	} else if failed(all, *strictExit) {
+		os.Exit(1)
+	}
+}
 
-	for _, f := range files {
-		info := types.Info{
-			Types: map[ast.Expr]types.TypeAndValue{},
-			Defs: map[*ast.Ident]types.Object{},
-			Uses: map[*ast.Ident]types.Object{},	
+// failed reports whether the findings should fail the run: when any is an
+// error or, with strict set, when there are any at all.
+func failed(diagnostics []checker.Diagnostic, strict bool) bool {
+	for _, d := range diagnostics {
+		if strict || d.Severity == analyzer.SeverityError {
+			return true
 		}
-		_, err = cfg.Check(f.path, fset, []*ast.File { f.node }, &info)
+	}
+	return false
+}
+
+// printDiffs prints a unified diff for each fixed file, in file name order,
+// labelled with paths relative to the working directory as git does.
+func printDiffs(fixed map[string][]byte, readFile func(string) ([]byte, error), relative func(string) string) {
+	filenames := make([]string, 0, len(fixed))
+	for filename := range fixed {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		original, err := readFile(filename)
 		if err != nil {
 			panic(err)
 		}
+		rel := relative(filename)
+		fmt.Print(diff.Unified("a/"+rel, "b/"+rel, original, fixed[filename]))
+	}
+}
 
-		ast.Walk(&visitor{
-			fset: fset,
-			info: info,
-		}, f.node)
+// writeFixes rewrites the files touched by suggested fixes.
+func writeFixes(fixed map[string][]byte, applied, skipped int) {
+	for filename, contents := range fixed {
+		info, err := os.Stat(filename)
+		if err != nil {
+			panic(err)
+		}
+		if err := os.WriteFile(filename, contents, info.Mode().Perm()); err != nil {
+			panic(err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "tsgo: applied %d fixes to %d files", applied, len(fixed))
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, " (%d skipped due to conflicting edits)", skipped)
 	}
+	fmt.Fprintln(os.Stderr)
 }
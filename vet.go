@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/rpetrich/tsgo/analyzer"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+// isVetInvocation reports whether tsgo is being run by go vet -vettool, which
+// first queries the tool with -V=full and -flags and then invokes it once per
+// package with the path of a JSON .cfg file describing the unit to analyze.
+func isVetInvocation(args []string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-V=") || arg == "-V" || arg == "-flags" {
+			return true
+		}
+	}
+	return len(args) > 0 && strings.HasSuffix(args[len(args)-1], ".cfg")
+}
+
+// vetMain hands control to unitchecker, letting the go command drive package
+// loading, build tags and caching of facts between packages. It does not
+// return.
+func vetMain() {
+	unitchecker.Main(analyzer.Analyzer)
+}
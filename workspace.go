@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// workspaceModuleDirs returns the directories of the modules listed in the
+// go.work file governing the current directory, or nil outside workspace mode.
+func workspaceModuleDirs(env []string) ([]string, error) {
+	cmd := exec.Command("go", "env", "GOWORK")
+	cmd.Env = env
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	gowork := strings.TrimSpace(string(out))
+	if gowork == "" || gowork == "off" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(gowork)
+	if err != nil {
+		return nil, err
+	}
+	work, err := modfile.ParseWork(gowork, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Dir(gowork)
+	dirs := make([]string, 0, len(work.Use))
+	for _, use := range work.Use {
+		dir := use.Path
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		dirs = append(dirs, filepath.Clean(dir))
+	}
+	return dirs, nil
+}
+
+// expandWorkspacePatterns rewrites relative recursive patterns such as ./...
+// into one pattern per workspace module beneath them. The go command rejects
+// ./... at a workspace root that is not itself a module, but running tsgo
+// there should analyze every module in the workspace.
+func expandWorkspacePatterns(patterns []string, moduleDirs []string) ([]string, error) {
+	if len(moduleDirs) == 0 {
+		return patterns, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	var expanded []string
+	for _, pattern := range patterns {
+		prefix, ok := strings.CutSuffix(pattern, "...")
+		if !ok || !strings.HasPrefix(prefix, ".") {
+			expanded = append(expanded, pattern)
+			continue
+		}
+		base := filepath.Join(cwd, filepath.FromSlash(prefix))
+		if containsModule(base, moduleDirs) {
+			expanded = append(expanded, pattern)
+			continue
+		}
+		matched := false
+		for _, dir := range moduleDirs {
+			rel, err := filepath.Rel(cwd, dir)
+			if err != nil || !isWithin(base, dir) {
+				continue
+			}
+			expanded = append(expanded, "./"+filepath.ToSlash(rel)+"/...")
+			matched = true
+		}
+		if !matched {
+			expanded = append(expanded, pattern)
+		}
+	}
+	return expanded, nil
+}
+
+// containsModule reports whether dir lies inside one of moduleDirs, in which
+// case the go command resolves patterns rooted there on its own.
+func containsModule(dir string, moduleDirs []string) bool {
+	for _, moduleDir := range moduleDirs {
+		if isWithin(moduleDir, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}